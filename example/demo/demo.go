@@ -21,20 +21,14 @@ func Demo(ctx context.Context) {
 	// logger := ilogger.GetLogger().WithField("func", "Demo")
 	logger := ilogger.FromContext(ctx)
 
-	// 创建可重用的字段
-	serviceField := zap.String("service", "payment")
-	versionField := zap.String("version", "1.2.3")
-
 	// 批量日志记录
 	for i := 0; i < 4; i++ {
-		// 继承 context 中的字段, 并添加额外的字段
+		// 继承 context 中的字段, 并添加额外的字段; service/version 等由配置中的 fields 统一添加
 		logger.Info("Payment processed",
-			serviceField,
-			versionField,
 			zap.Int("transaction_id", i),
 			zap.Float64("amount", float64(i)*0.99),
 		)
-		// {"level":"info","time":"2025-06-08T18:35:28.962763039+08:00","caller":"demo/demo.go:28","msg":"Payment processed","func":"Demo","X-Request-Id":"test-trace-5","service":"payment","version":"1.2.3","transaction_id":3,"amount":2.9699999999999998}
+		// {"level":"info","time":"2025-06-08T18:35:28.962763039+08:00","caller":"demo/demo.go:28","msg":"Payment processed","host":"vm","pid":4242,"service":"zap-demo","version":"dev","env":"dev","func":"Demo","X-Request-Id":"test-trace-5","transaction_id":3,"amount":2.9699999999999998}
 	}
 
 	// 使用上下文日志器
@@ -57,8 +51,8 @@ func Demo(ctx context.Context) {
 }
 
 func subDemo(ctx context.Context) {
-	l := ilogger.FromContext(ctx)
-	logger := l.WithFieldsMap(map[string]any{"func": "subDemo"})
-	logger.Info("i am sub demo")
+	// FromContext/InfoCtx 会合并 context 中的 logger 和字段, 无需再手动添加
+	ctx = ilogger.StoreFieldsInContext(ctx, zap.String("func", "subDemo"))
+	ilogger.InfoCtx(ctx, "i am sub demo")
 	// {"level":"info","time":"2025-06-08T18:35:28.991516226+08:00","caller":"demo/demo.go:55","msg":"i am sub demo","func":"subDemo","X-Request-Id":"test-trace-5","child":"myson"}
 }
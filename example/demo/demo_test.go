@@ -1,23 +1,39 @@
-package demo
+package demo_test
 
 import (
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"sync"
 	"testing"
-	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/double12gzh/zap-demo/logger/logtest"
+	"github.com/double12gzh/zap-demo/router"
 	"github.com/double12gzh/zap-demo/router/middleware"
 )
 
 func TestConcurrentPing(t *testing.T) {
+	prevMode, prevWriter := gin.Mode(), gin.DefaultWriter
+	t.Cleanup(func() {
+		gin.SetMode(prevMode)
+		gin.DefaultWriter = prevWriter
+	})
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+
+	l, logs := logtest.New(t, logtest.WithLevel(zapcore.InfoLevel))
+	engine := router.NewEngine(router.WithLogger(l))
+
 	// Number of concurrent requests
 	numRequests := 10
 	var wg sync.WaitGroup
 
-	// Create a channel to collect errors
-	errChan := make(chan error, numRequests)
-
 	// Launch concurrent requests
 	for i := 0; i < numRequests; i++ {
 		wg.Add(1)
@@ -27,44 +43,38 @@ func TestConcurrentPing(t *testing.T) {
 			// Create a unique trace ID for each request
 			traceID := fmt.Sprintf("test-trace-%d", requestNum)
 
-			// Create a new request
-			req, err := http.NewRequest("GET", "http://localhost:8080/ping", nil)
-			if err != nil {
-				errChan <- fmt.Errorf("failed to create request: %v", err)
-				return
-			}
-
+			req := httptest.NewRequest(http.MethodGet, "/ping", nil)
 			req.Header.Set(middleware.RequestIDHeader, traceID)
-
-			// Create HTTP client with timeout
-			client := &http.Client{
-				Timeout: 5 * time.Second,
-			}
-
-			// Send request
-			resp, err := client.Do(req)
-			if err != nil {
-				errChan <- fmt.Errorf("request failed: %v", err)
-				return
-			}
-			defer resp.Body.Close()
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
 
 			// Check response status
-			if resp.StatusCode != http.StatusOK {
-				errChan <- fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-				return
-			}
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, traceID, w.Header().Get(middleware.RequestIDHeader))
 		}(i)
 	}
 
 	// Wait for all requests to complete
 	wg.Wait()
-	close(errChan)
+	logs.RequireNoErrors()
 
-	// Check for any errors
-	for err := range errChan {
-		if err != nil {
-			t.Errorf("Test failed: %v", err)
+	// every entry carries the request id of the request that logged it
+	perRequest := map[string]map[string]int{}
+	for _, entry := range logs.All() {
+		reqID, ok := entry.ContextMap()[middleware.RequestIDHeader].(string)
+		require.True(t, ok, "entry %q has no request id", entry.Message)
+		if perRequest[reqID] == nil {
+			perRequest[reqID] = map[string]int{}
 		}
+		perRequest[reqID][entry.Message]++
+	}
+
+	require.Len(t, perRequest, numRequests)
+	for i := 0; i < numRequests; i++ {
+		assert.Equal(t, map[string]int{
+			"Payment processed":       4,
+			"Database query executed": 5,
+			"i am sub demo":           1,
+		}, perRequest[fmt.Sprintf("test-trace-%d", i)])
 	}
 }
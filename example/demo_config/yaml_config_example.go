@@ -1,7 +1,9 @@
 package demo_config
 
 import (
+	"context"
 	"path/filepath"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -32,8 +34,10 @@ func DemoConfig() {
 	sugar := log.GetSugaredLogger()
 	sugar.Infof("Hello, %s!", "World")
 
-	// Close logger
-	if err := log.Close(); err != nil {
+	// Close logger, flushing buffered entries within 5 seconds
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := log.Close(ctx); err != nil {
 		panic(err)
 	}
 }
@@ -1,34 +1,163 @@
 package logger
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"reflect"
 
 	"gopkg.in/yaml.v3"
 )
 
-// YamlConfig represents the YAML configuration structure
+// YamlConfig represents the YAML configuration structure. The include and
+// profile keys are handled while the files are loaded, see LoadConfigFromYaml.
 type YamlConfig struct {
 	Logger Config `yaml:"logger"`
 }
 
-// LoadConfigFromYaml loads logger configuration from a YAML file
+// LoadConfigFromYaml loads logger configuration from a YAML file.
+//
+// The file can include other files and select a profile overlay, such as
+// log.prod.yaml for log.yaml, by its profile key or AppEnvVar:
+//
+//	include: [common.yaml]   # relative to the including file
+//	profile: dev             # APP_ENV=prod loads log.prod.yaml instead
+//	logger:
+//	  level: debug
+//
+// Files are merged key by key, the later one winning, explicit zero values
+// included: max_backups: 0 in an overlay replaces the 7 of the base file, and
+// max_backups: ~ drops it to get the default back. See configLoader for the
+// exact order.
+//
+// Scalar values may contain ${VAR} and ${VAR:-default} placeholders, which
+// are replaced by environment variables; use $${ for a literal ${. Every value
+// can then be overridden by an EnvPrefix variable such as LOGGER_MAX_SIZE,
+// empty variables are ignored.
+//
+// Unknown keys, values of the wrong type, values rejected by Config.Validate
+// and log directories that cannot be written are all reported together in a
+// *ValidationError, with the YAML path and line of each problem.
 func LoadConfigFromYaml(configPath string) (*Config, error) {
-	// Read the YAML file
-	data, err := os.ReadFile(configPath)
+	config, _, err := LoadConfigFromYamlWithSources(configPath)
+	return config, err
+}
+
+// LoadConfigFromYamlWithSources is like LoadConfigFromYaml and also reports
+// where each value of the config comes from.
+func LoadConfigFromYamlWithSources(configPath string) (*Config, ConfigSources, error) {
+	return loadConfig(configPath, FormatYAML)
+}
+
+// LoadConfig loads logger configuration from a YAML, JSON or TOML file, chosen
+// by the extension of configPath, .yaml, .yml, .json or .toml, or else by its
+// content. The file is processed like LoadConfigFromYaml does whatever its
+// format: the keys are the same, includes and profile overlays may use any
+// format, and the values are validated the same way.
+//
+//	{"logger": {"level": "info", "max_size": "100MiB"}}
+//
+//	[logger]
+//	level = "info"
+//	max_size = "100MiB"
+func LoadConfig(configPath string) (*Config, error) {
+	config, _, err := loadConfig(configPath, FormatAuto)
+	return config, err
+}
+
+// LoadConfigFromReader is like LoadConfig for a config read from r in format,
+// detected from the content for FormatAuto. Includes are relative to the
+// working directory. Profile overlays need a config file, so the profile key
+// is rejected and AppEnvVar is ignored.
+func LoadConfigFromReader(r io.Reader, format Format) (*Config, error) {
+	data, err := io.ReadAll(r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
+	ld := newConfigLoader()
+	doc, err := ld.loadData(readerName, data, format)
+	if err != nil {
+		return nil, err
+	}
+	if key, _ := popKey(doc, "profile"); key != nil {
+		ld.errs = append(ld.errs, ld.files.errorAt(key, "profile", "profile overlays need a config file, use LoadConfig"))
+	}
+	config, _, err := ld.decode(readerName, doc)
+	return config, err
+}
+
+// readerName stands for the config read by LoadConfigFromReader in errors and
+// sources.
+const readerName = "<reader>"
+
+// loadConfig loads the config file at configPath, see LoadConfigFromYaml.
+func loadConfig(configPath string, format Format) (*Config, ConfigSources, error) {
+	ld := newConfigLoader()
+	doc, err := ld.load(configPath, format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config, sources, err := ld.decode(configPath, doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", configPath, err)
+	}
+	return config, sources, nil
+}
+
+// decode overrides, strictly decodes and validates the config document loaded
+// from the file name.
+func (ld *configLoader) decode(name string, doc *yaml.Node) (*Config, ConfigSources, error) {
+	errs := ld.errs
+	pos := yamlPositions{}
+	errs = append(errs, checkKeys(doc, reflect.TypeOf(YamlConfig{}), "", pos, ld.files)...)
+
+	sources := ConfigSources{}
+	for _, leaf := range configLeaves(reflect.TypeOf(Config{}), "") {
+		source := Source{}
+		if n, ok := pos["logger."+leaf.path]; ok {
+			source = Source{File: ld.files[n], Line: n.Line, Vars: ld.vars[n]}
+		}
+		sources[leaf.path] = source
+	}
+	errs = append(errs, applyEnvOverrides(doc, pos, sources)...)
 
-	// Parse the YAML data
 	var yamlConfig YamlConfig
-	if err := yaml.Unmarshal(data, &yamlConfig); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	if err := doc.Decode(&yamlConfig); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+		leafErrs := leafTypeErrors(pos, ld.files)
+		if len(leafErrs) == 0 {
+			leafErrs = typeErrors(typeErr, pos)
+		}
+		errs = append(errs, leafErrs...)
 	}
 
 	// Convert YAML config to logger Config
 	config := &yamlConfig.Logger
-	return config, nil
+	var invalid *ValidationError
+	if errors.As(config.Validate(), &invalid) {
+		for _, fe := range pos.locate("logger", invalid.Errors, sources, ld.files) {
+			if !hasFieldError(errs, fe.Path) {
+				// a value that failed to decode is already reported
+				errs = append(errs, fe)
+			}
+		}
+	}
+	errs = append(errs, pos.locate("logger", config.checkWritable(), sources, ld.files)...)
+	if len(errs) > 0 {
+		for _, fe := range errs {
+			if fe.File == name {
+				fe.File = ""
+			}
+		}
+		sortFieldErrors(errs)
+		return nil, nil, &ValidationError{Errors: errs}
+	}
+	return config, sources, nil
 }
 
 // InitLoggerFromYaml initializes the logger from a YAML configuration file
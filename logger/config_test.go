@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfig writes content to a config file in a temp dir, replacing $DIR
+// with that dir.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(strings.ReplaceAll(content, "$DIR", dir)), 0o644))
+	return path
+}
+
+func TestLoadConfigFromYamlValidation(t *testing.T) {
+	path := writeConfig(t, `
+logger:
+  level: verbose
+  filename: $DIR/app.log
+  max_sizes: 100
+  max_backups: -1
+  compres: true
+  buffer_size: big
+  async_flush_interval: -5
+`)
+
+	_, err := LoadConfigFromYaml(path)
+	var invalid *ValidationError
+	require.ErrorAs(t, err, &invalid)
+
+	var got []string
+	for _, fe := range invalid.Errors {
+		got = append(got, fe.Error())
+	}
+	assert.Equal(t, []string{
+		`logger.level (line 3, column 10): unknown level "verbose", expected one of debug, info, warn, error, panic, fatal`,
+		`logger.max_sizes (line 5, column 3): unknown key "max_sizes", did you mean "max_size"?`,
+		`logger.max_backups (line 6, column 16): must not be negative, got -1`,
+		`logger.compres (line 7, column 3): unknown key "compres", did you mean "compress"?`,
+		`logger.buffer_size (line 8, column 16): invalid size "big", expected a number with an optional unit such as 256KiB`,
+		`logger.async_flush_interval (line 9, column 25): must be positive, got -5ms`,
+	}, got)
+	assert.Contains(t, err.Error(), path+": invalid logger config, 6 problems:\n\t")
+}
+
+func TestLoadConfigFromYamlUnknownSection(t *testing.T) {
+	path := writeConfig(t, `
+loger:
+  level: info
+`)
+	_, err := LoadConfigFromYaml(path)
+	assert.EqualError(t, err, path+`: invalid logger config: loger (line 2, column 1): unknown key "loger", did you mean "logger"?`)
+
+	// no suggestion when nothing is close
+	path = writeConfig(t, `
+logger:
+  colour: red
+`)
+	_, err = LoadConfigFromYaml(path)
+	assert.EqualError(t, err, path+`: invalid logger config: logger.colour (line 3, column 3): unknown key "colour"`)
+}
+
+func TestLoadConfigFromYamlUnwritableDir(t *testing.T) {
+	// a file where the log directory should be
+	path := writeConfig(t, `
+logger:
+  filename: $DIR/log.yaml/app.log
+  error_filename: $DIR/logs/nested/error.log
+`)
+	_, err := LoadConfigFromYaml(path)
+	var invalid *ValidationError
+	require.ErrorAs(t, err, &invalid)
+	require.Len(t, invalid.Errors, 1)
+	assert.Equal(t, "logger.filename", invalid.Errors[0].Path)
+	assert.Equal(t, 3, invalid.Errors[0].Line)
+	assert.Contains(t, invalid.Errors[0].Message, "is not a directory")
+
+	// the missing directory was not created by the check
+	_, err = os.Stat(filepath.Join(filepath.Dir(path), "logs"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestLoadConfigFromYamlSyntaxError(t *testing.T) {
+	path := writeConfig(t, "logger:\n  level: [info\n")
+	_, err := LoadConfigFromYaml(path)
+	assert.ErrorContains(t, err, "failed to parse config file")
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, (&Config{}).Validate())
+	assert.NoError(t, defaultConfig().Validate())
+
+	err := (&Config{Level: "loud", MaxSize: Ptr[FileSize](-MiB), MaxBackups: Ptr(0), AsyncBufferSize: Ptr[ByteSize](0)}).Validate()
+	assert.EqualError(t, err, "invalid logger config, 3 problems:\n"+
+		"\tlevel: unknown level \"loud\", expected one of debug, info, warn, error, panic, fatal\n"+
+		"\tmax_size: must be positive, got -1MiB\n"+
+		"\tasync_buffer_size: must be positive, got 0B")
+
+	_, err = NewLogger(&Config{MaxAge: Ptr(Age(-Day)), Filename: filepath.Join(t.TempDir(), "app.log")})
+	assert.EqualError(t, err, "invalid logger config: max_age: must not be negative, got -1d")
+}
+
+func TestLevenshtein(t *testing.T) {
+	assert.Equal(t, 0, levenshtein("max_size", "max_size"))
+	assert.Equal(t, 1, levenshtein("max_sizes", "max_size"))
+	assert.Equal(t, 2, levenshtein("max_aeg", "max_age"))
+	assert.Equal(t, 3, levenshtein("", "abc"))
+}
+
+func TestLoadConfigFromYamlExplicitZeros(t *testing.T) {
+	path := writeConfig(t, `
+logger:
+  filename: $DIR/app.log
+  max_backups: 0
+  buffer_size: 0
+  compress: false
+  max_age: ~
+`)
+	config, err := LoadConfigFromYaml(path)
+	require.NoError(t, err)
+
+	merged := mergeConfigWithDefault(config)
+	assert.Equal(t, Ptr(0), merged.MaxBackups)
+	assert.Equal(t, Ptr[ByteSize](0), merged.BufferSize)
+	assert.Equal(t, Ptr(false), merged.Compress)
+	// unset and null values take the defaults
+	assert.Equal(t, Ptr[Age](30*Age(Day)), merged.MaxAge)
+	assert.Equal(t, Ptr[FileSize](100*MiB), merged.MaxSize)
+	assert.Equal(t, Ptr(Duration(time.Second)), merged.AsyncFlushInterval)
+}
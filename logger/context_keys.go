@@ -2,30 +2,121 @@ package logger
 
 import (
 	"context"
+	"sync/atomic"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 type ctxLogFieldsKey struct{}
 
+// fieldNode is the context returned by StoreFieldsInContext. It is its own
+// value for ctxLogFieldsKey, which saves the allocation of context.WithValue.
+//
+// all holds every field of the chain, oldest first. The first child of a node
+// appends its fields to the backing array of all, like appending to a slice
+// that is not shared would, and the next children copy it. Every element of a
+// backing array is thus written once, before the node owning it is published,
+// and sibling contexts never see the fields of each other.
+type fieldNode struct {
+	context.Context
+	all     []zap.Field
+	claimed atomic.Bool // set by the child appending to all in place
+
+	// set by Logger.WithContext, so only the contexts logged with pay for it
+	cache atomic.Pointer[fieldCache]
+}
+
+// fieldCache holds what is derived from the fields of a node.
+type fieldCache struct {
+	flat         []zap.Field // de-duplicated fields
+	from, logger *Logger     // logger is from.WithContext of the node
+}
+
+func (n *fieldNode) Value(key any) any {
+	if _, ok := key.(ctxLogFieldsKey); ok {
+		return n
+	}
+	return n.Context.Value(key)
+}
+
+// StoreFieldsInContext returns a copy of ctx carrying fields in addition to the
+// fields already stored in ctx. ctx itself and its other children are never
+// modified, so it is safe to branch contexts from concurrent goroutines.
 func StoreFieldsInContext(ctx context.Context, fields ...zap.Field) context.Context {
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	existingFields := GetFieldsFromContext(ctx)
-	return context.WithValue(ctx, ctxLogFieldsKey{}, append(existingFields, fields...))
+	if len(fields) == 0 {
+		return ctx
+	}
+
+	var all []zap.Field
+	if parent := fieldNodeOf(ctx); parent != nil {
+		all = parent.all
+		if !parent.claimed.CompareAndSwap(false, true) {
+			// a sibling appends in place already, force a copy
+			all = all[:len(all):len(all)]
+		}
+	}
+	// append copies the fields, the caller owns the variadic slice
+	return &fieldNode{Context: ctx, all: append(all, fields...)}
 }
 
+// GetFieldsFromContext returns the fields stored in ctx, oldest first. When a
+// key was stored more than once only the latest value is kept. The returned
+// slice is shared and must not be modified; appending to it is safe.
 func GetFieldsFromContext(ctx context.Context) []zap.Field {
 	if ctx == nil {
 		return nil
 	}
-	val := ctx.Value(ctxLogFieldsKey{})
-	if val == nil {
+	n := fieldNodeOf(ctx)
+	if n == nil {
 		return nil
 	}
-	if fs, ok := val.([]zap.Field); ok {
-		return fs
+	return n.flatten()
+}
+
+func fieldNodeOf(ctx context.Context) *fieldNode {
+	n, _ := ctx.Value(ctxLogFieldsKey{}).(*fieldNode)
+	return n
+}
+
+// flatten returns the de-duplicated fields of n, the ones cached by
+// Logger.WithContext when there are.
+func (n *fieldNode) flatten() []zap.Field {
+	if c := n.cache.Load(); c != nil {
+		return c.flat
+	}
+	return uniqueFields(n.all)
+}
+
+// uniqueFields returns fields without the ones dedupeFields removes, capped
+// so that appending to the result copies it. fields is not modified, and is
+// returned itself when nothing is removed.
+func uniqueFields(fields []zap.Field) []zap.Field {
+	if len(fields) > dedupeFieldsMapThreshold {
+		out := dedupeFields(append([]zap.Field(nil), fields...))
+		return out[:len(out):len(out)]
+	}
+
+	keep := func(i int) bool {
+		return fields[i].Type == zapcore.NamespaceType || !keyRepeated(fields, i)
+	}
+	kept := 0
+	for i := range fields {
+		if keep(i) {
+			kept++
+		}
+	}
+	if kept == len(fields) {
+		return fields[:kept:kept]
+	}
+	out := make([]zap.Field, 0, kept)
+	for i, f := range fields {
+		if keep(i) {
+			out = append(out, f)
+		}
 	}
-	return nil
+	return out
 }
@@ -0,0 +1,238 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFromContextMergesFields(t *testing.T) {
+	l, buf := newBufferLogger(t)
+	l = l.WithFields(zap.String("func", "Demo"), zap.String("X-Request-Id", "from-logger"))
+
+	ctx := NewContextWithValue(context.Background(), l)
+	ctx = StoreFieldsInContext(ctx, zap.String("child", "myson"))
+	ctx = StoreFieldsInContext(ctx, zap.String("X-Request-Id", "from-context"))
+
+	FromContext(ctx).Info("merged")
+	InfoCtx(ctx, "package level")
+
+	lines := decodeLines(t, buf.String(), timeKey)
+	require.Len(t, lines, 2)
+	for _, line := range lines {
+		assert.Equal(t, "Demo", line["func"])
+		assert.Equal(t, "myson", line["child"])
+		assert.Equal(t, "from-context", line["X-Request-Id"])
+		assert.Contains(t, line[callerKey], "logger/context_test.go:")
+	}
+
+	// the logger stored in the context is not modified
+	l.Info("original")
+	lines = decodeLines(t, buf.String(), timeKey, callerKey)
+	assert.Equal(t, "from-logger", lines[2]["X-Request-Id"])
+	assert.NotContains(t, lines[2], "child")
+}
+
+func TestPackageLevelCtxFunctions(t *testing.T) {
+	l, buf := newBufferLogger(t)
+	ctx := NewContextWithValue(context.Background(), l)
+	ctx = StoreFieldsInContext(ctx, zap.String("k", "v"))
+
+	DebugCtx(ctx, "d")
+	InfoCtx(ctx, "i")
+	WarnCtx(ctx, "w")
+	ErrorCtx(ctx, "e")
+
+	lines := decodeLines(t, buf.String(), timeKey, callerKey, stacktraceKey)
+	require.Len(t, lines, 4)
+	for i, level := range []string{"debug", "info", "warn", "error"} {
+		assert.Equal(t, level, lines[i][levelKey])
+		assert.Equal(t, "v", lines[i]["k"])
+	}
+}
+
+func TestMergeFields(t *testing.T) {
+	keys := func(fields []zap.Field) []string {
+		var out []string
+		for _, f := range fields {
+			out = append(out, f.Key)
+		}
+		return out
+	}
+
+	base := []zap.Field{zap.String("a", "1"), zap.String("b", "1")}
+	merged := mergeFields(base, []zap.Field{zap.String("a", "2"), zap.String("c", "2")})
+	assert.Equal(t, []string{"b", "a", "c"}, keys(merged))
+	assert.Equal(t, "2", merged[1].String)
+	// inputs are untouched
+	assert.Equal(t, "1", base[0].String)
+
+	// keys in a namespace do not collide with top-level keys
+	merged = mergeFields([]zap.Field{zap.String("a", "1"), zap.Namespace("ns"), zap.String("a", "2")}, nil)
+	assert.Equal(t, []string{"a", "ns", "a"}, keys(merged))
+
+	merged = mergeFields(nil, []zap.Field{zap.String("a", "1"), zap.String("a", "2")})
+	require.Len(t, merged, 1)
+	assert.Equal(t, "2", merged[0].String)
+}
+
+func TestStoreFieldsInContextSiblings(t *testing.T) {
+	parent := StoreFieldsInContext(context.Background(), zap.String("a", "1"), zap.String("b", "1"), zap.String("c", "1"))
+
+	// both siblings append to the same parent; the old slice based store let the
+	// second call overwrite the first sibling's field through the shared array
+	left := StoreFieldsInContext(parent, zap.String("side", "left"))
+	right := StoreFieldsInContext(parent, zap.String("side", "right"))
+
+	assert.Equal(t, "left", GetFieldsFromContext(left)[3].String)
+	assert.Equal(t, "right", GetFieldsFromContext(right)[3].String)
+	assert.Len(t, GetFieldsFromContext(parent), 3)
+
+	// appending to a returned slice must not leak into other contexts
+	_ = append(GetFieldsFromContext(parent), zap.String("side", "leak"))
+	assert.Equal(t, "left", GetFieldsFromContext(left)[3].String)
+
+	// the first child appends in place to the spare capacity of its parent,
+	// the second one copies
+	parent = context.Background()
+	for _, k := range []string{"a", "b", "c"} {
+		parent = StoreFieldsInContext(parent, zap.String(k, "1"))
+	}
+	require.Greater(t, cap(fieldNodeOf(parent).all), 3)
+	left = StoreFieldsInContext(parent, zap.String("side", "left"))
+	right = StoreFieldsInContext(parent, zap.String("side", "right"))
+	assert.Same(t, &fieldNodeOf(parent).all[0], &fieldNodeOf(left).all[0])
+	assert.NotSame(t, &fieldNodeOf(parent).all[0], &fieldNodeOf(right).all[0])
+	assert.Equal(t, "left", GetFieldsFromContext(left)[3].String)
+	assert.Equal(t, "right", GetFieldsFromContext(right)[3].String)
+	assert.Len(t, GetFieldsFromContext(parent), 3)
+}
+
+func TestFromContextCachesLogger(t *testing.T) {
+	l, buf := newBufferLogger(t)
+	ctx := NewContextWithValue(context.Background(), l)
+	ctx = StoreFieldsInContext(ctx, zap.String("request_id", "r1"))
+
+	first := FromContext(ctx)
+	assert.Same(t, first, FromContext(ctx))
+	assert.Zero(t, testing.AllocsPerRun(100, func() {
+		FromContext(ctx)
+	}))
+
+	// another logger gets its own derived logger
+	other := l.WithFields(zap.String("service", "demo"))
+	derived := other.WithContext(ctx)
+	assert.NotSame(t, first, derived)
+	derived.Info("other")
+	FromContext(ctx).Info("again")
+
+	lines := decodeLines(t, buf.String(), timeKey, callerKey)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "demo", lines[0]["service"])
+	assert.Equal(t, "r1", lines[0]["request_id"])
+	assert.NotContains(t, lines[1], "service")
+	assert.Equal(t, "r1", lines[1]["request_id"])
+}
+
+func TestStoreFieldsInContextDedup(t *testing.T) {
+	ctx := StoreFieldsInContext(context.Background(), zap.String("a", "1"), zap.String("b", "1"))
+	ctx = StoreFieldsInContext(ctx, zap.String("a", "2"))
+
+	fields := GetFieldsFromContext(ctx)
+	require.Len(t, fields, 2)
+	assert.Equal(t, "b", fields[0].Key)
+	assert.Equal(t, "a", fields[1].Key)
+	assert.Equal(t, "2", fields[1].String)
+
+	assert.Nil(t, GetFieldsFromContext(context.Background()))
+	assert.Same(t, ctx, StoreFieldsInContext(ctx))
+}
+
+func TestStoreFieldsInContextConcurrentSiblings(t *testing.T) {
+	parent := StoreFieldsInContext(context.Background(), zap.String("request", "r1"))
+
+	const workers = 64
+	var wg sync.WaitGroup
+	errs := make(chan string, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx := parent
+			for depth := 0; depth < 10; depth++ {
+				ctx = StoreFieldsInContext(ctx, zap.Int("worker", i), zap.Int("depth", depth))
+				fields := GetFieldsFromContext(ctx)
+				if len(fields) != 3 || fields[1].Integer != int64(i) || fields[2].Integer != int64(depth) {
+					errs <- fmt.Sprintf("worker %d depth %d saw %v", i, depth, fields)
+					return
+				}
+				// concurrent readers of the shared parent
+				if got := GetFieldsFromContext(parent); len(got) != 1 {
+					errs <- fmt.Sprintf("parent changed: %v", got)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// legacyStoreFieldsInContext is the previous slice based implementation, kept
+// to compare allocations in the benchmarks below.
+func legacyStoreFieldsInContext(ctx context.Context, fields ...zap.Field) context.Context {
+	existing, _ := ctx.Value(ctxLogFieldsKey{}).([]zap.Field)
+	return context.WithValue(ctx, ctxLogFieldsKey{}, append(existing, fields...))
+}
+
+// The benchmarks simulate one request adding a field at every layer and then
+// reading them once, as FromContext does.
+const benchmarkDepth = 8
+
+func BenchmarkStoreFieldsInContext(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ctx := context.Background()
+		for depth := 0; depth < benchmarkDepth; depth++ {
+			ctx = StoreFieldsInContext(ctx, zap.Int("depth", depth))
+		}
+		if len(GetFieldsFromContext(ctx)) == 0 {
+			b.Fatal("no fields")
+		}
+	}
+}
+
+func BenchmarkStoreFieldsInContextLegacy(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ctx := context.Background()
+		for depth := 0; depth < benchmarkDepth; depth++ {
+			ctx = legacyStoreFieldsInContext(ctx, zap.Int("depth", depth))
+		}
+		if fields, _ := ctx.Value(ctxLogFieldsKey{}).([]zap.Field); len(fields) == 0 {
+			b.Fatal("no fields")
+		}
+	}
+}
+
+func TestDedupeFieldsLarge(t *testing.T) {
+	var fields []zap.Field
+	for i := 0; i < dedupeFieldsMapThreshold*2; i++ {
+		fields = append(fields, zap.Int(fmt.Sprintf("k%d", i%10), i))
+	}
+	fields = append(fields, zap.Namespace("ns"), zap.Int("k0", -1))
+
+	out := dedupeFields(append([]zap.Field(nil), fields...))
+	require.Len(t, out, 12)
+	assert.Equal(t, int64(len(fields)-3), out[9].Integer)
+	assert.Equal(t, "ns", out[10].Key)
+	assert.Equal(t, int64(-1), out[11].Integer)
+}
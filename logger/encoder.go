@@ -0,0 +1,275 @@
+package logger
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// EncodingConfig controls how entries are written to the log files.
+//
+// The key fields are pointers so that an empty key, which leaves the value out
+// of every entry, is told apart from an unset one. The encoders are chosen by
+// name, an empty name takes the default. A preset sets the keys and encoders
+// of a log backend, the values set next to it win. The console shares the
+// keys and encoders, but keeps its colored levels.
+type EncodingConfig struct {
+	Preset string `json:"preset" yaml:"preset"` // ecs, gcp or datadog, see PresetECS, PresetGCP and PresetDatadog
+
+	MessageKey    *string `json:"message_key" yaml:"message_key"`       // msg
+	LevelKey      *string `json:"level_key" yaml:"level_key"`           // level
+	TimeKey       *string `json:"time_key" yaml:"time_key"`             // time
+	NameKey       *string `json:"name_key" yaml:"name_key"`             // logger
+	CallerKey     *string `json:"caller_key" yaml:"caller_key"`         // caller, unless disable_caller is set
+	FunctionKey   *string `json:"function_key" yaml:"function_key"`     // empty, the function is left out
+	StacktraceKey *string `json:"stacktrace_key" yaml:"stacktrace_key"` // stacktrace, unless disable_stacktrace is set
+
+	Level          string `json:"level" yaml:"level"`                       // lowercase, capital, lowercase_color or capital_color
+	Time           string `json:"time" yaml:"time"`                         // layout for time_format, rfc3339, rfc3339nano, iso8601, epoch, epoch_millis or epoch_nanos
+	Duration       string `json:"duration" yaml:"duration"`                 // seconds, millis, nanos or string
+	Caller         string `json:"caller" yaml:"caller"`                     // short or full
+	Name           string `json:"name" yaml:"name"`                         // full
+	LineEnding     string `json:"line_ending" yaml:"line_ending"`           // lf or crlf
+	SkipZeroValues bool   `json:"skip_zero_values" yaml:"skip_zero_values"` // leave out fields holding 0, false, "" or nil
+}
+
+var levelEncoders = map[string]zapcore.LevelEncoder{
+	"lowercase":       zapcore.LowercaseLevelEncoder,
+	"capital":         zapcore.CapitalLevelEncoder,
+	"lowercase_color": zapcore.LowercaseColorLevelEncoder,
+	"capital_color":   zapcore.CapitalColorLevelEncoder,
+	"gcp":             gcpSeverityEncoder,
+}
+
+// timeEncoders holds the time encoders but layout, which depends on the
+// time_format of the config.
+var timeEncoders = map[string]zapcore.TimeEncoder{
+	"rfc3339":      zapcore.RFC3339TimeEncoder,
+	"rfc3339nano":  zapcore.RFC3339NanoTimeEncoder,
+	"iso8601":      zapcore.ISO8601TimeEncoder,
+	"epoch":        zapcore.EpochTimeEncoder,
+	"epoch_millis": zapcore.EpochMillisTimeEncoder,
+	"epoch_nanos":  zapcore.EpochNanosTimeEncoder,
+}
+
+const layoutTimeEncoder = "layout"
+
+// lookupEncoder returns the encoder of name in encoders, or the one of def when
+// name is unknown.
+func lookupEncoder[T any](encoders map[string]T, name, def string) T {
+	if enc, ok := encoders[name]; ok {
+		return enc
+	}
+	return encoders[def]
+}
+
+var durationEncoders = map[string]zapcore.DurationEncoder{
+	"seconds": zapcore.SecondsDurationEncoder,
+	"millis":  zapcore.MillisDurationEncoder,
+	"nanos":   zapcore.NanosDurationEncoder,
+	"string":  zapcore.StringDurationEncoder,
+}
+
+var callerEncoders = map[string]zapcore.CallerEncoder{
+	"short": zapcore.ShortCallerEncoder,
+	"full":  zapcore.FullCallerEncoder,
+}
+
+var nameEncoders = map[string]zapcore.NameEncoder{
+	"full": zapcore.FullNameEncoder,
+}
+
+var lineEndings = map[string]string{
+	"lf":   "\n",
+	"crlf": "\r\n",
+}
+
+// validate reports the unknown encoder names of e, with paths below encoder.
+func (e *EncodingConfig) validate() []*FieldError {
+	timeNames := append(slices.Collect(maps.Keys(timeEncoders)), layoutTimeEncoder)
+
+	var errs []*FieldError
+	for _, v := range []struct {
+		path  string
+		value string
+		names []string
+	}{
+		{"level", e.Level, slices.Collect(maps.Keys(levelEncoders))},
+		{"time", e.Time, timeNames},
+		{"duration", e.Duration, slices.Collect(maps.Keys(durationEncoders))},
+		{"caller", e.Caller, slices.Collect(maps.Keys(callerEncoders))},
+		{"name", e.Name, slices.Collect(maps.Keys(nameEncoders))},
+		{"line_ending", e.LineEnding, slices.Collect(maps.Keys(lineEndings))},
+		{"preset", e.Preset, slices.Collect(maps.Keys(presetEncodings))},
+	} {
+		if v.value == "" || slices.Contains(v.names, v.value) {
+			continue
+		}
+		slices.Sort(v.names)
+		what := strings.ReplaceAll(v.path, "_", " ") + " encoder"
+		if v.path == "preset" {
+			what = "preset"
+		}
+		errs = append(errs, &FieldError{
+			Path:    "encoder." + v.path,
+			Message: fmt.Sprintf("unknown %s %q, expected one of %s", what, v.value, strings.Join(v.names, ", ")),
+		})
+	}
+	return errs
+}
+
+// entryKeys returns the keys written by the encoder for the entry itself,
+// which fields cannot use.
+func (e *EncodingConfig) entryKeys() map[string]bool {
+	keys := map[string]bool{}
+	for _, v := range []struct {
+		key *string
+		def string
+	}{
+		{e.MessageKey, messageKey},
+		{e.LevelKey, levelKey},
+		{e.TimeKey, timeKey},
+		{e.NameKey, nameKey},
+		{e.CallerKey, callerKey},
+		{e.FunctionKey, ""},
+		{e.StacktraceKey, stacktraceKey},
+	} {
+		key := v.def
+		if v.key != nil {
+			key = *v.key
+		}
+		if key != "" {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// pickEncoding sets the values of dst that are set in v, see mergeConfig.
+func pickEncoding(dst *EncodingConfig, v EncodingConfig) {
+	pick(&dst.Preset, v.Preset)
+	pickPtr(&dst.MessageKey, v.MessageKey)
+	pickPtr(&dst.LevelKey, v.LevelKey)
+	pickPtr(&dst.TimeKey, v.TimeKey)
+	pickPtr(&dst.NameKey, v.NameKey)
+	pickPtr(&dst.CallerKey, v.CallerKey)
+	pickPtr(&dst.FunctionKey, v.FunctionKey)
+	pickPtr(&dst.StacktraceKey, v.StacktraceKey)
+	pick(&dst.Level, v.Level)
+	pick(&dst.Time, v.Time)
+	pick(&dst.Duration, v.Duration)
+	pick(&dst.Caller, v.Caller)
+	pick(&dst.Name, v.Name)
+	pick(&dst.LineEnding, v.LineEnding)
+	pick(&dst.SkipZeroValues, v.SkipZeroValues)
+}
+
+// NewEncoder returns the encoder NewLogger uses for the log files described by
+// config, with unset values defaulted. config is not modified.
+func NewEncoder(config *Config) zapcore.Encoder {
+	c := mergeConfigWithDefault(config)
+	return newEncoder(c, zapcore.NewJSONEncoder(newEncoderConfig(c)))
+}
+
+// newEncoder wraps enc according to the encoder settings of c.
+func newEncoder(c *Config, enc zapcore.Encoder) zapcore.Encoder {
+	if c.Encoder.SkipZeroValues {
+		enc = &skipZeroEncoder{Encoder: enc}
+	}
+	if _, ok := presetEncodings[c.Encoder.Preset]; ok {
+		// outermost, so the values rewritten by the preset are checked too
+		enc = newPresetEncoder(c, enc)
+	}
+	return enc
+}
+
+// skipZeroEncoder leaves out the fields holding the zero value of their type.
+// Only the top level fields of an entry are checked, objects and arrays are
+// written as they are.
+type skipZeroEncoder struct {
+	zapcore.Encoder
+}
+
+func (e *skipZeroEncoder) Clone() zapcore.Encoder {
+	return &skipZeroEncoder{Encoder: e.Encoder.Clone()}
+}
+
+func (e *skipZeroEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	if len(fields) == 0 {
+		return e.Encoder.EncodeEntry(ent, nil)
+	}
+	// the fields go through the filtering methods below, then are written as
+	// context of the entry, where the encoder would have put them anyway
+	clone := e.Clone().(*skipZeroEncoder)
+	for _, f := range fields {
+		f.AddTo(clone)
+	}
+	return clone.Encoder.EncodeEntry(ent, nil)
+}
+
+func (e *skipZeroEncoder) AddBinary(key string, v []byte) {
+	if len(v) > 0 {
+		e.Encoder.AddBinary(key, v)
+	}
+}
+
+func (e *skipZeroEncoder) AddByteString(key string, v []byte) {
+	if len(v) > 0 {
+		e.Encoder.AddByteString(key, v)
+	}
+}
+
+func (e *skipZeroEncoder) AddBool(key string, v bool) { addNonZero(e.Encoder.AddBool, key, v) }
+func (e *skipZeroEncoder) AddString(key string, v string) {
+	addNonZero(e.Encoder.AddString, key, v)
+}
+func (e *skipZeroEncoder) AddDuration(key string, v time.Duration) {
+	addNonZero(e.Encoder.AddDuration, key, v)
+}
+
+func (e *skipZeroEncoder) AddFloat64(key string, v float64) { addNonZero(e.Encoder.AddFloat64, key, v) }
+func (e *skipZeroEncoder) AddFloat32(key string, v float32) { addNonZero(e.Encoder.AddFloat32, key, v) }
+func (e *skipZeroEncoder) AddComplex128(key string, v complex128) {
+	addNonZero(e.Encoder.AddComplex128, key, v)
+}
+func (e *skipZeroEncoder) AddComplex64(key string, v complex64) {
+	addNonZero(e.Encoder.AddComplex64, key, v)
+}
+
+func (e *skipZeroEncoder) AddInt(key string, v int)     { addNonZero(e.Encoder.AddInt, key, v) }
+func (e *skipZeroEncoder) AddInt64(key string, v int64) { addNonZero(e.Encoder.AddInt64, key, v) }
+func (e *skipZeroEncoder) AddInt32(key string, v int32) { addNonZero(e.Encoder.AddInt32, key, v) }
+func (e *skipZeroEncoder) AddInt16(key string, v int16) { addNonZero(e.Encoder.AddInt16, key, v) }
+func (e *skipZeroEncoder) AddInt8(key string, v int8)   { addNonZero(e.Encoder.AddInt8, key, v) }
+
+func (e *skipZeroEncoder) AddUint(key string, v uint)       { addNonZero(e.Encoder.AddUint, key, v) }
+func (e *skipZeroEncoder) AddUint64(key string, v uint64)   { addNonZero(e.Encoder.AddUint64, key, v) }
+func (e *skipZeroEncoder) AddUint32(key string, v uint32)   { addNonZero(e.Encoder.AddUint32, key, v) }
+func (e *skipZeroEncoder) AddUint16(key string, v uint16)   { addNonZero(e.Encoder.AddUint16, key, v) }
+func (e *skipZeroEncoder) AddUint8(key string, v uint8)     { addNonZero(e.Encoder.AddUint8, key, v) }
+func (e *skipZeroEncoder) AddUintptr(key string, v uintptr) { addNonZero(e.Encoder.AddUintptr, key, v) }
+
+func (e *skipZeroEncoder) AddTime(key string, v time.Time) {
+	if !v.IsZero() {
+		e.Encoder.AddTime(key, v)
+	}
+}
+
+func (e *skipZeroEncoder) AddReflected(key string, v any) error {
+	if v == nil {
+		return nil
+	}
+	return e.Encoder.AddReflected(key, v)
+}
+
+func addNonZero[T comparable](add func(string, T), key string, v T) {
+	var zero T
+	if v != zero {
+		add(key, v)
+	}
+}
@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLoggerEncoding(t *testing.T) {
+	path := writeConfig(t, `
+logger:
+  filename: $DIR/app.log
+  error_filename: $DIR/error.log
+  console: false
+  disable_stacktrace: true
+  encoder:
+    time_key: "@timestamp"
+    level_key: severity
+    message_key: message
+    name_key: ""
+    level: capital
+    time: epoch_millis
+    duration: millis
+    caller: full
+    line_ending: crlf
+`)
+	config, err := LoadConfigFromYaml(path)
+	require.NoError(t, err)
+	log, err := NewLogger(config)
+	require.NoError(t, err)
+
+	log.Info("processed", zap.Duration("took", 1500*time.Millisecond))
+	require.NoError(t, log.Close(context.Background()))
+
+	appLog := filepath.Join(filepath.Dir(path), "app.log")
+	out, err := os.ReadFile(appLog)
+	require.NoError(t, err)
+	line := string(out)
+	assert.True(t, strings.HasSuffix(line, "}\r\n"), "%q", line)
+	assert.Regexp(t, `^\{"severity":"INFO","@timestamp":\d+\.?\d*,"caller":"/.+/logger/encoder_test\.go:\d+","message":"processed","took":1500\}`, line)
+
+	entries := readEntries(t, appLog)
+	require.Len(t, entries, 1)
+	assert.NotContains(t, entries[0], "logger")
+	assert.InDelta(t, float64(time.Now().UnixMilli()), entries[0]["@timestamp"], float64(time.Minute.Milliseconds()))
+}
+
+func TestNewEncoderConfigDefaults(t *testing.T) {
+	ec := NewEncoderConfig(nil)
+	assert.Equal(t, "time", ec.TimeKey)
+	assert.Equal(t, "level", ec.LevelKey)
+	assert.Equal(t, "msg", ec.MessageKey)
+	assert.Equal(t, "logger", ec.NameKey)
+	assert.Equal(t, "caller", ec.CallerKey)
+	assert.Equal(t, "", ec.FunctionKey)
+	assert.Equal(t, "stacktrace", ec.StacktraceKey)
+	assert.Equal(t, "\n", ec.LineEnding)
+
+	ec = NewEncoderConfig(&Config{DisableCaller: true, Encoder: EncodingConfig{CallerKey: Ptr("src"), FunctionKey: Ptr("func")}})
+	assert.Equal(t, "", ec.CallerKey)
+	assert.Equal(t, "func", ec.FunctionKey)
+}
+
+func TestSkipZeroValues(t *testing.T) {
+	enc := NewEncoder(&Config{
+		DisableCaller: true,
+		Encoder:       EncodingConfig{TimeKey: Ptr(""), SkipZeroValues: true},
+	})
+	enc.AddInt("retries", 0)
+	enc.AddString("user", "alice")
+
+	buf, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.InfoLevel, Message: "done"}, []zapcore.Field{
+		zap.String("empty", ""),
+		zap.Int("count", 0),
+		zap.Bool("ok", false),
+		zap.Float64("ratio", 0),
+		zap.Duration("took", 0),
+		zap.Time("at", time.Time{}),
+		zap.Any("extra", nil),
+		zap.Error(nil),
+		zap.Int("rows", 3),
+		zap.Namespace("db"),
+		zap.Strings("tables", nil),
+		zap.Bool("cached", true),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `{"level":"info","msg":"done","user":"alice","rows":3,"db":{"tables":[],"cached":true}}`+"\n", buf.String())
+
+	// the encoder itself is left alone
+	buf, err = enc.EncodeEntry(zapcore.Entry{Level: zapcore.InfoLevel, Message: "again"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `{"level":"info","msg":"again","user":"alice"}`+"\n", buf.String())
+}
+
+func TestLoadConfigFromYamlEncoderErrors(t *testing.T) {
+	path := writeConfig(t, `
+logger:
+  filename: $DIR/app.log
+  fields:
+    message: hello
+    msg: kept
+  encoder:
+    message_key: message
+    level: loud
+    time: unix
+    line_ending: cr
+    skip_zero_value: true
+`)
+	_, err := LoadConfigFromYaml(path)
+	var invalid *ValidationError
+	require.ErrorAs(t, err, &invalid)
+	var got []string
+	for _, fe := range invalid.Errors {
+		got = append(got, fe.Error())
+	}
+	assert.Equal(t, []string{
+		`logger.fields (line 5, column 5): key "message" is used by every entry`,
+		`logger.encoder.level (line 9, column 12): unknown level encoder "loud", expected one of capital, capital_color, gcp, lowercase, lowercase_color`,
+		`logger.encoder.time (line 10, column 11): unknown time encoder "unix", expected one of epoch, epoch_millis, epoch_nanos, iso8601, layout, rfc3339, rfc3339nano`,
+		`logger.encoder.line_ending (line 11, column 18): unknown line ending encoder "cr", expected one of crlf, lf`,
+		`logger.encoder.skip_zero_value (line 12, column 5): unknown key "skip_zero_value", did you mean "skip_zero_values"?`,
+	}, got)
+}
+
+func TestMergeConfigEncoding(t *testing.T) {
+	base := &Config{Encoder: EncodingConfig{TimeKey: Ptr("@timestamp"), Duration: "millis", SkipZeroValues: true}}
+	over := &Config{Encoder: EncodingConfig{TimeKey: Ptr(""), Level: "capital"}}
+
+	merged := mergeConfigWithDefault(mergeConfig(base, over)).Encoder
+	assert.Equal(t, Ptr(""), merged.TimeKey)
+	assert.Equal(t, Ptr("msg"), merged.MessageKey)
+	assert.Equal(t, "capital", merged.Level)
+	assert.Equal(t, "millis", merged.Duration)
+	assert.Equal(t, layoutTimeEncoder, merged.Time)
+	assert.True(t, merged.SkipZeroValues)
+}
+
+func TestNewEncoderUnknownNames(t *testing.T) {
+	// only Validate reports unknown names, the encoders fall back to the defaults
+	enc := NewEncoder(&Config{Encoder: EncodingConfig{
+		Preset:     "splunk",
+		TimeKey:    Ptr(""),
+		Level:      "Loud",
+		Time:       "unix",
+		Duration:   "hours",
+		Caller:     "Full",
+		Name:       "short",
+		LineEnding: "cr",
+	}})
+	buf, err := enc.EncodeEntry(zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		LoggerName: "app",
+		Message:    "hello",
+		Caller:     zapcore.NewEntryCaller(0, "/src/app/logger/main.go", 42, true),
+	}, []zapcore.Field{zap.Duration("took", 1500*time.Millisecond)})
+	require.NoError(t, err)
+	assert.Equal(t, `{"level":"warn","logger":"app","caller":"logger/main.go:42","msg":"hello","took":1.5}`+"\n", buf.String())
+
+	ec := NewEncoderConfig(&Config{Encoder: EncodingConfig{Time: "unix"}})
+	assert.NotNil(t, ec.EncodeTime)
+}
@@ -0,0 +1,278 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix prefixes the environment variables overriding config values. The
+// variable of a key is the prefix followed by its YAML path below logger in
+// upper case, with dots replaced by underscores: LOGGER_MAX_SIZE overrides
+// logger.max_size. Maps such as logger.fields are written as comma separated
+// key=value pairs, LOGGER_FIELDS=service=api,team=core replaces the whole map.
+const EnvPrefix = "LOGGER_"
+
+// Source tells where the effective value of a config key comes from.
+type Source struct {
+	File string   // config file holding the value
+	Line int      // line of the value in File
+	Vars []string // environment variables interpolated into the file value
+	Env  string   // environment variable overriding the file, set by EnvPrefix
+}
+
+func (s Source) String() string {
+	switch {
+	case s.Env != "":
+		return "env " + s.Env
+	case s.File != "" && len(s.Vars) > 0:
+		return fmt.Sprintf("%s:%d (${%s})", s.File, s.Line, strings.Join(s.Vars, "}, ${"))
+	case s.File != "":
+		return fmt.Sprintf("%s:%d", s.File, s.Line)
+	default:
+		return "default"
+	}
+}
+
+// ConfigSources maps the YAML path of every Config value, such as max_size, to
+// its Source. Values that are not set anywhere have the zero Source, they get
+// their default when the logger is created.
+type ConfigSources map[string]Source
+
+// String lists the sources one per line, sorted by path.
+func (s ConfigSources) String() string {
+	paths := make([]string, 0, len(s))
+	for p := range s {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var b strings.Builder
+	for _, p := range paths {
+		fmt.Fprintf(&b, "%s: %s\n", p, s[p])
+	}
+	return b.String()
+}
+
+// expandEnv replaces the ${VAR} and ${VAR:-default} placeholders in the
+// scalar values below n and returns the variables used by each replaced node.
+// ${VAR:-default} uses default when VAR is unset or empty, ${VAR} fails when
+// VAR is unset. $${ stands for a literal ${.
+func expandEnv(n *yaml.Node, path string, vars map[*yaml.Node][]string) []*FieldError {
+	var errs []*FieldError
+	switch n.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for i, c := range n.Content {
+			p := path
+			if n.Kind == yaml.SequenceNode {
+				p = fmt.Sprintf("%s[%d]", path, i)
+			}
+			errs = append(errs, expandEnv(c, p, vars)...)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			errs = append(errs, expandEnv(n.Content[i+1], joinPath(path, n.Content[i].Value), vars)...)
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(n.Value, "${") {
+			break
+		}
+		value, used, err := interpolate(n.Value)
+		if err != nil {
+			errs = append(errs, &FieldError{Path: path, Line: n.Line, Column: n.Column, Message: err.Error()})
+			break
+		}
+		n.Value = value
+		if n.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+			// resolve the type of the expanded value, like it was written in the file
+			n.Tag = ""
+		}
+		if len(used) > 0 {
+			vars[n] = used
+		}
+	}
+	return errs
+}
+
+// interpolate expands the placeholders of s, see expandEnv.
+func interpolate(s string) (string, []string, error) {
+	var (
+		b    strings.Builder
+		used []string
+	)
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), used, nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			// escaped
+			b.WriteString(s[:i-1])
+			b.WriteString("${")
+			s = s[i+2:]
+			continue
+		}
+		b.WriteString(s[:i])
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", nil, fmt.Errorf("unterminated placeholder in %q", s)
+		}
+		expr := s[i+2 : i+end]
+		s = s[i+end+1:]
+
+		name, def, hasDefault := strings.Cut(expr, ":-")
+		if !validEnvName(name) {
+			return "", nil, fmt.Errorf("invalid environment variable name %q", name)
+		}
+		used = append(used, name)
+		value, ok := os.LookupEnv(name)
+		switch {
+		case hasDefault && value == "":
+			value = def
+		case !ok:
+			return "", nil, fmt.Errorf("environment variable %s is not set, use ${%s:-default} to provide a default", name, name)
+		}
+		b.WriteString(value)
+	}
+}
+
+func validEnvName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, c := range name {
+		if c != '_' && (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// configLeaf is a value of Config that can be set from a single scalar.
+type configLeaf struct {
+	path string // YAML path below logger
+	typ  reflect.Type
+}
+
+// configLeaves lists the values of struct type t, descending into nested
+// structs unless they decode themselves.
+func configLeaves(t reflect.Type, path string) []configLeaf {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || reflect.PointerTo(t).Implements(yamlUnmarshalerType) {
+		return []configLeaf{{path: path, typ: t}}
+	}
+	fields := yamlFields(t)
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var leaves []configLeaf
+	for _, name := range names {
+		leaves = append(leaves, configLeaves(fields[name].Type, joinPath(path, name))...)
+	}
+	return leaves
+}
+
+// envName returns the override variable of the Config value at path.
+func envName(path string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// applyEnvOverrides sets the logger values of doc that have a non-empty
+// EnvPrefix variable. Each value is checked against the type of its field
+// before it is set, so a bad variable is reported by name.
+func applyEnvOverrides(doc *yaml.Node, pos yamlPositions, sources ConfigSources) []*FieldError {
+	var errs []*FieldError
+	for _, leaf := range configLeaves(reflect.TypeOf(Config{}), "") {
+		name := envName(leaf.path)
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		n, err := envNode(leaf.typ, value)
+		if err == nil {
+			err = n.Decode(reflect.New(leaf.typ).Interface())
+		}
+		if err != nil {
+			errs = append(errs, &FieldError{
+				Path:    "logger." + leaf.path,
+				Message: fmt.Sprintf("%s: %s", name, yamlErrorMessage(err)),
+			})
+			continue
+		}
+		pos["logger."+leaf.path] = setNode(doc, "logger."+leaf.path, n)
+		sources[leaf.path] = Source{Env: name}
+	}
+	return errs
+}
+
+// envNode builds the node of an override value for a field of type t.
+func envNode(t reflect.Type, value string) (*yaml.Node, error) {
+	if t.Kind() != reflect.Map {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: value}, nil
+	}
+	n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, pair := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid pair %q, expected key=value", pair)
+		}
+		n.Content = append(n.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: strings.TrimSpace(v)})
+	}
+	return n, nil
+}
+
+// setNode sets the value at the dotted path of the mapping doc, creating the
+// intermediate mappings, and returns the node holding it.
+func setNode(doc *yaml.Node, path string, value *yaml.Node) *yaml.Node {
+	n := doc
+	keys := strings.Split(path, ".")
+	for i, key := range keys {
+		if n.Kind != yaml.MappingNode {
+			// missing or null section
+			*n = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		var child *yaml.Node
+		for j := 0; j+1 < len(n.Content); j += 2 {
+			if n.Content[j].Value == key {
+				child = n.Content[j+1]
+			}
+		}
+		if child == nil {
+			child = &yaml.Node{}
+			n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+		}
+		if i == len(keys)-1 {
+			*child = *value
+			return child
+		}
+		n = child
+	}
+	return n
+}
+
+// yamlErrorMessage returns the message of a yaml error without its position,
+// which is meaningless for values built from the environment.
+func yamlErrorMessage(err error) string {
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		msg := typeErr.Errors[0]
+		if m := yamlLineRe.FindStringSubmatch(msg); m != nil {
+			return m[2]
+		}
+		return msg
+	}
+	return err.Error()
+}
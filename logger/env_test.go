@@ -0,0 +1,164 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("LOG_DIR", "/var/log/app")
+	t.Setenv("EMPTY", "")
+
+	tests := []struct {
+		in   string
+		want string
+		used []string
+		err  string
+	}{
+		{in: "${LOG_DIR}/app.log", want: "/var/log/app/app.log", used: []string{"LOG_DIR"}},
+		{in: "${UNSET_VAR:-/tmp}/app.log", want: "/tmp/app.log", used: []string{"UNSET_VAR"}},
+		{in: "${EMPTY:-fallback}", want: "fallback", used: []string{"EMPTY"}},
+		{in: "${EMPTY}", want: "", used: []string{"EMPTY"}},
+		{in: "${UNSET_VAR:-}", want: "", used: []string{"UNSET_VAR"}},
+		{in: "$${LOG_DIR} ${LOG_DIR}", want: "${LOG_DIR} /var/log/app", used: []string{"LOG_DIR"}},
+		{in: "a-${LOG_DIR:-x}-${UNSET_VAR:-y}", want: "a-/var/log/app-y", used: []string{"LOG_DIR", "UNSET_VAR"}},
+		{in: "${UNSET_VAR}", err: "environment variable UNSET_VAR is not set, use ${UNSET_VAR:-default} to provide a default"},
+		{in: "${LOG_DIR", err: `unterminated placeholder in "${LOG_DIR"`},
+		{in: "${1ABC}", err: `invalid environment variable name "1ABC"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, used, err := interpolate(tt.in)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.used, used)
+		})
+	}
+}
+
+func TestLoadConfigFromYamlEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("LOG_DIR", dir)
+	t.Setenv("APP_LEVEL", "warn")
+	t.Setenv("LOGGER_MAX_BACKUPS", "7")
+	t.Setenv("LOGGER_COMPRESS", "false")
+	t.Setenv("LOGGER_TIME_FORMAT", "")
+
+	path := writeConfig(t, `
+logger:
+  level: ${APP_LEVEL:-info}
+  filename: ${LOG_DIR}/app.log
+  time_format: "${TIME_FORMAT:-2006-01-02}"
+  max_size: ${MAX_SIZE:-50}
+  max_backups: 3
+  compress: true
+  console: $${not_a_var}
+`)
+	_, _, err := LoadConfigFromYamlWithSources(path)
+	// console is a bool, the escaped placeholder stays a string
+	assert.ErrorContains(t, err, "logger.console (line 9, column 12): cannot unmarshal !!str `${not_a...` into bool")
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+logger:
+  level: ${APP_LEVEL:-info}
+  filename: ${LOG_DIR}/app.log
+  time_format: "${TIME_FORMAT:-2006-01-02}"
+  max_size: ${MAX_SIZE:-50}
+  max_backups: 3
+  compress: true
+`), 0o644))
+	config, sources, err := LoadConfigFromYamlWithSources(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, LogLevelWarn, config.Level)
+	assert.Equal(t, filepath.Join(dir, "app.log"), config.Filename)
+	assert.Equal(t, "2006-01-02", config.TimeFormat)
+	assert.Equal(t, Ptr[FileSize](50*MiB), config.MaxSize)
+	assert.Equal(t, Ptr(7), config.MaxBackups)
+	assert.Equal(t, Ptr(false), config.Compress)
+
+	assert.Equal(t, path+":3 (${APP_LEVEL})", sources["level"].String())
+	assert.Equal(t, path+":6 (${MAX_SIZE})", sources["max_size"].String())
+	assert.Equal(t, "env LOGGER_MAX_BACKUPS", sources["max_backups"].String())
+	assert.Equal(t, "env LOGGER_COMPRESS", sources["compress"].String())
+	// empty variables do not override
+	assert.Equal(t, path+":5 (${TIME_FORMAT})", sources["time_format"].String())
+	assert.Equal(t, "default", sources["max_age"].String())
+	// every Config value has a source, even the unset ones
+	assert.Len(t, sources, len(configLeaves(reflect.TypeOf(Config{}), "")))
+	assert.Contains(t, sources, "encoder.preset")
+	assert.Contains(t, sources.String(), "max_backups: env LOGGER_MAX_BACKUPS\nmax_size: "+path+":6 (${MAX_SIZE})\n")
+}
+
+func TestLoadConfigFromYamlEnvOnly(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("LOGGER_LEVEL", "debug")
+	t.Setenv("LOGGER_FILENAME", filepath.Join(dir, "app.log"))
+	t.Setenv("LOGGER_ENABLE_ASYNC", "true")
+
+	for _, content := range []string{"", "logger:\n"} {
+		config, sources, err := LoadConfigFromYamlWithSources(writeConfig(t, content))
+		require.NoError(t, err)
+		assert.Equal(t, LogLevelDebug, config.Level)
+		assert.Equal(t, filepath.Join(dir, "app.log"), config.Filename)
+		assert.True(t, config.EnableAsync)
+		assert.Equal(t, Source{Env: "LOGGER_ENABLE_ASYNC"}, sources["enable_async"])
+	}
+}
+
+func TestLoadConfigFromYamlEnvFields(t *testing.T) {
+	t.Setenv("LOGGER_FIELDS", "team=core, version=1.10,empty=")
+
+	path := writeConfig(t, `
+logger:
+  fields:
+    service: api
+`)
+	config, sources, err := LoadConfigFromYamlWithSources(path)
+	require.NoError(t, err)
+	// the variable replaces the map of the file
+	assert.Equal(t, map[string]string{"team": "core", "version": "1.10", "empty": ""}, config.Fields)
+	assert.Equal(t, Source{Env: "LOGGER_FIELDS"}, sources["fields"])
+
+	t.Setenv("LOGGER_FIELDS", "team")
+	_, err = LoadConfigFromYaml(path)
+	assert.ErrorContains(t, err, `logger.fields: LOGGER_FIELDS: invalid pair "team", expected key=value`)
+}
+
+func TestLoadConfigFromYamlEnvErrors(t *testing.T) {
+	t.Setenv("LOGGER_MAX_SIZE", "big")
+	t.Setenv("LOGGER_MAX_AGE", "-1")
+
+	path := writeConfig(t, `
+logger:
+  filename: ${MISSING_DIR}/app.log
+  max_age: 30
+`)
+	_, err := LoadConfigFromYaml(path)
+	var invalid *ValidationError
+	require.ErrorAs(t, err, &invalid)
+
+	var got []string
+	for _, fe := range invalid.Errors {
+		got = append(got, fe.Error())
+	}
+	assert.Equal(t, []string{
+		"logger.filename (line 3, column 13): environment variable MISSING_DIR is not set, use ${MISSING_DIR:-default} to provide a default",
+		`logger.max_size: LOGGER_MAX_SIZE: invalid size "big", expected a number with an optional unit such as 256KiB`,
+		"logger.max_age: LOGGER_MAX_AGE: must not be negative, got -1d",
+	}, got)
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "LOGGER_MAX_SIZE", envName("max_size"))
+	assert.Equal(t, "LOGGER_ENCODER_PRESET", envName("encoder.preset"))
+}
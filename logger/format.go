@@ -0,0 +1,241 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the syntax of a config file.
+type Format string
+
+const (
+	// FormatAuto detects the format from the file extension, or from the
+	// content when the extension is not known.
+	FormatAuto Format = ""
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+	FormatTOML Format = "toml"
+)
+
+// formatOf returns the format of the file name holding data.
+func formatOf(name string, data []byte) Format {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".json":
+		return FormatJSON
+	case ".toml":
+		return FormatTOML
+	}
+	return sniffFormat(data)
+}
+
+// tomlLineRe matches the table headers and key/value pairs of TOML.
+var tomlLineRe = regexp.MustCompile(`^(\[\[?\s*[\w.\-"' ]+\]\]?\s*(#.*)?$|[\w.\-"']+\s*=)`)
+
+// sniffFormat guesses the format of data from its first significant line.
+func sniffFormat(data []byte) Format {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		switch {
+		case line[0] == '{':
+			return FormatJSON
+		case tomlLineRe.MatchString(line):
+			return FormatTOML
+		}
+		return FormatYAML
+	}
+	return FormatYAML
+}
+
+// parseDocument parses data into the YAML document every format is loaded
+// through. An empty document is an empty mapping.
+//
+// JSON and TOML strings holding ${VAR} placeholders are turned into plain YAML
+// scalars, so the expanded value takes the type it reads as, like an unquoted
+// value in YAML: "${MAX_BACKUPS:-5}" is the number 5. Other strings stay
+// strings.
+func parseDocument(data []byte, format Format) (*yaml.Node, error) {
+	switch format {
+	case FormatYAML:
+		return parseYAML(data)
+	case FormatJSON:
+		if !json.Valid(data) {
+			var v any
+			err := json.Unmarshal(data, &v)
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				line, col := offsetPosition(data, syntaxErr.Offset)
+				return nil, fmt.Errorf("line %d, column %d: %w", line, col, err)
+			}
+			return nil, err
+		}
+		// JSON is YAML, parsing it as such keeps the positions of the values
+		doc, err := parseYAML(data)
+		if err != nil {
+			return nil, err
+		}
+		unquotePlaceholders(doc)
+		return doc, nil
+	case FormatTOML:
+		return parseTOML(data)
+	default:
+		return nil, fmt.Errorf("unknown config format %q, expected yaml, json or toml", format)
+	}
+}
+
+func parseYAML(data []byte) (*yaml.Node, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	return root.Content[0], nil
+}
+
+// unquotePlaceholders clears the quoting style of the strings below n that
+// hold placeholders.
+func unquotePlaceholders(n *yaml.Node) {
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!str" && strings.Contains(n.Value, "${") {
+		n.Style = 0
+	}
+	for _, c := range n.Content {
+		unquotePlaceholders(c)
+	}
+}
+
+// offsetPosition returns the line and column of the byte offset in data.
+func offsetPosition(data []byte, offset int64) (int, int) {
+	lead := string(data[:min(int(offset), len(data))])
+	return strings.Count(lead, "\n") + 1, len(lead) - strings.LastIndex(lead, "\n")
+}
+
+// parseTOML decodes data and converts the values into YAML nodes, positioned
+// at their key.
+func parseTOML(data []byte) (*yaml.Node, error) {
+	var values map[string]any
+	if err := toml.Unmarshal(data, &values); err != nil {
+		var decodeErr *toml.DecodeError
+		if errors.As(err, &decodeErr) {
+			line, col := decodeErr.Position()
+			return nil, fmt.Errorf("line %d, column %d: %w", line, col, err)
+		}
+		return nil, err
+	}
+	return tomlNode(values, "", tomlPositions(data)), nil
+}
+
+// tomlPositions returns the position of every key of the TOML document data
+// by its dotted path. Keys inside inline tables and arrays are left out.
+func tomlPositions(data []byte) map[string]unstable.Position {
+	positions := map[string]unstable.Position{}
+	var (
+		p     unstable.Parser
+		table string
+	)
+	p.Reset(data)
+	for p.NextExpression() {
+		e := p.Expression()
+		if e.Kind != unstable.Table && e.Kind != unstable.ArrayTable && e.Kind != unstable.KeyValue {
+			continue
+		}
+		var (
+			keys []string
+			pos  unstable.Position
+		)
+		for it := e.Key(); it.Next(); {
+			k := it.Node()
+			if len(keys) == 0 {
+				pos = p.Shape(k.Raw).Start
+			}
+			keys = append(keys, string(k.Data))
+		}
+		path := strings.Join(keys, ".")
+		if e.Kind == unstable.KeyValue {
+			path = joinPath(table, path)
+		} else {
+			table = path
+		}
+		if _, ok := positions[path]; !ok {
+			positions[path] = pos
+		}
+	}
+	return positions
+}
+
+// tomlNode converts the decoded TOML value v at path into a YAML node.
+func tomlNode(v any, path string, positions map[string]unstable.Position) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.ScalarNode}
+	if pos, ok := positions[path]; ok {
+		n.Line, n.Column = pos.Line, pos.Column
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		n.Kind, n.Tag = yaml.MappingNode, "!!map"
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		// in document order, keys without a position last
+		sort.Slice(keys, func(i, j int) bool {
+			pi, iok := positions[joinPath(path, keys[i])]
+			pj, jok := positions[joinPath(path, keys[j])]
+			if iok != jok || (iok && pi.Offset != pj.Offset) {
+				return iok && (!jok || pi.Offset < pj.Offset)
+			}
+			return keys[i] < keys[j]
+		})
+		for _, k := range keys {
+			key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}
+			value := tomlNode(v[k], joinPath(path, k), positions)
+			key.Line, key.Column = value.Line, value.Column
+			n.Content = append(n.Content, key, value)
+		}
+	case []any:
+		n.Kind, n.Tag = yaml.SequenceNode, "!!seq"
+		for _, item := range v {
+			c := tomlNode(item, "", nil)
+			c.Line, c.Column = n.Line, n.Column
+			n.Content = append(n.Content, c)
+		}
+	case string:
+		n.Tag, n.Value, n.Style = "!!str", v, yaml.DoubleQuotedStyle
+		if strings.Contains(v, "${") {
+			n.Style = 0
+		}
+	case bool:
+		n.Tag, n.Value = "!!bool", strconv.FormatBool(v)
+	case int64:
+		n.Tag, n.Value = "!!int", strconv.FormatInt(v, 10)
+	case float64:
+		n.Tag, n.Value = "!!float", strconv.FormatFloat(v, 'g', -1, 64)
+		switch {
+		case math.IsNaN(v):
+			n.Value = ".nan"
+		case math.IsInf(v, 1):
+			n.Value = ".inf"
+		case math.IsInf(v, -1):
+			n.Value = "-.inf"
+		}
+	default:
+		// dates and times
+		n.Tag, n.Value = "!!str", fmt.Sprint(v)
+	}
+	return n
+}
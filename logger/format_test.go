@@ -0,0 +1,238 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// every Config field, written the same way in each format
+var fullConfigs = map[Format]string{
+	FormatYAML: `
+logger:
+  level: warn
+  filename: $DIR/app.log
+  error_filename: $DIR/error.log
+  time_format: 2006-01-02
+  max_size: 50MiB
+  max_backups: 0
+  max_age: 7d
+  buffer_size: 0
+  compress: false
+  console: false
+  disable_caller: true
+  disable_stacktrace: true
+  enable_async: true
+  async_buffer_size: 64KiB
+  async_flush_interval: 250
+  fields:
+    service: payment
+  metadata:
+    pid: true
+    kubernetes: false
+  encoder:
+    time_key: "@timestamp"
+    duration: millis
+`,
+	FormatJSON: `{
+	"logger": {
+		"level": "warn",
+		"filename": "$DIR/app.log",
+		"error_filename": "$DIR/error.log",
+		"time_format": "2006-01-02",
+		"max_size": "50MiB",
+		"max_backups": 0,
+		"max_age": "7d",
+		"buffer_size": 0,
+		"compress": false,
+		"console": false,
+		"disable_caller": true,
+		"disable_stacktrace": true,
+		"enable_async": true,
+		"async_buffer_size": "64KiB",
+		"async_flush_interval": 250,
+		"fields": {"service": "payment"},
+		"metadata": {"pid": true, "kubernetes": false},
+		"encoder": {"time_key": "@timestamp", "duration": "millis"}
+	}
+}
+`,
+	FormatTOML: `
+[logger]
+level = "warn"
+filename = "$DIR/app.log"
+error_filename = "$DIR/error.log"
+time_format = "2006-01-02"
+max_size = "50MiB"
+max_backups = 0
+max_age = "7d"
+buffer_size = 0
+compress = false
+console = false
+disable_caller = true
+disable_stacktrace = true
+enable_async = true
+async_buffer_size = "64KiB"
+async_flush_interval = 250
+
+[logger.fields]
+service = "payment"
+
+[logger.metadata]
+pid = true
+kubernetes = false
+
+[logger.encoder]
+time_key = "@timestamp"
+duration = "millis"
+`,
+}
+
+func fullConfig(dir string) *Config {
+	return &Config{
+		Level:              LogLevelWarn,
+		Filename:           filepath.Join(dir, "app.log"),
+		ErrorFilename:      filepath.Join(dir, "error.log"),
+		TimeFormat:         "2006-01-02",
+		MaxSize:            Ptr[FileSize](50 * MiB),
+		MaxBackups:         Ptr(0),
+		MaxAge:             Ptr(Age(7 * Day)),
+		BufferSize:         Ptr[ByteSize](0),
+		Compress:           Ptr(false),
+		Console:            Ptr(false),
+		DisableCaller:      true,
+		DisableStacktrace:  true,
+		EnableAsync:        true,
+		AsyncBufferSize:    Ptr[ByteSize](64 * KiB),
+		AsyncFlushInterval: Ptr(Duration(250 * time.Millisecond)),
+		Fields:             map[string]string{"service": "payment"},
+		Metadata:           MetadataConfig{PID: Ptr(true), Kubernetes: Ptr(false)},
+		Encoder:            EncodingConfig{TimeKey: Ptr("@timestamp"), Duration: "millis"},
+	}
+}
+
+func TestLoadConfigFormats(t *testing.T) {
+	for format, content := range fullConfigs {
+		t.Run(string(format), func(t *testing.T) {
+			dir := t.TempDir()
+			content = strings.ReplaceAll(content, "$DIR", dir)
+
+			// by extension
+			path := filepath.Join(dir, "log."+string(format))
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+			config, err := LoadConfig(path)
+			require.NoError(t, err)
+			assert.Equal(t, fullConfig(dir), config)
+
+			// by content
+			path = filepath.Join(dir, "log.conf")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+			config, err = LoadConfig(path)
+			require.NoError(t, err)
+			assert.Equal(t, fullConfig(dir), config)
+
+			for _, f := range []Format{format, FormatAuto} {
+				config, err = LoadConfigFromReader(strings.NewReader(content), f)
+				require.NoError(t, err)
+				assert.Equal(t, fullConfig(dir), config)
+			}
+		})
+	}
+}
+
+func TestSniffFormat(t *testing.T) {
+	tests := map[string]Format{
+		"":                             FormatYAML,
+		"logger:\n  level: info\n":     FormatYAML,
+		"# comment\n{\"logger\": {}}":  FormatJSON,
+		"  {}":                         FormatJSON,
+		"[logger]\nlevel = 'info'\n":   FormatTOML,
+		"# comment\n\nprofile = 'dev'": FormatTOML,
+		"logger.level = 'info'":        FormatTOML,
+		"- a\n- b\n":                   FormatYAML,
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, sniffFormat([]byte(in)), "%q", in)
+	}
+}
+
+func TestLoadConfigFormatErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	// syntax errors are positioned in every format
+	_, err := LoadConfig(write("bad.json", "{\n  \"logger\": {\"level\": }\n}"))
+	assert.ErrorContains(t, err, "bad.json: failed to parse config file: line 2, column 24: invalid character '}' looking for beginning of value")
+	_, err = LoadConfig(write("bad.toml", "[logger]\nlevel = \n"))
+	assert.ErrorContains(t, err, "bad.toml: failed to parse config file: line 2, column 9: toml: ")
+
+	// as are the invalid values
+	_, err = LoadConfig(write("invalid.toml", `
+[logger]
+level = "loud"
+max_backups = "many"
+colour = "red"
+`))
+	var invalid *ValidationError
+	require.ErrorAs(t, err, &invalid)
+	var got []string
+	for _, fe := range invalid.Errors {
+		got = append(got, fe.Error())
+	}
+	assert.Equal(t, []string{
+		`logger.level (line 3, column 1): unknown level "loud", expected one of debug, info, warn, error, panic, fatal`,
+		"logger.max_backups (line 4, column 1): cannot unmarshal !!str `many` into int",
+		`logger.colour (line 5, column 1): unknown key "colour"`,
+	}, got)
+
+	_, err = LoadConfig(write("invalid.json", `{"logger": {"max_age": -1, "compress": "maybe"}}`))
+	assert.EqualError(t, err, filepath.Join(dir, "invalid.json")+": invalid logger config, 2 problems:\n"+
+		"\tlogger.max_age (line 1, column 24): must not be negative, got -1d\n"+
+		"\tlogger.compress (line 1, column 40): cannot unmarshal !!str `maybe` into bool")
+
+	_, err = LoadConfigFromReader(strings.NewReader("profile = 'prod'\n"), FormatAuto)
+	assert.EqualError(t, err, `invalid logger config: profile (line 1, column 1): profile overlays need a config file, use LoadConfig`)
+
+	_, err = LoadConfigFromReader(strings.NewReader("{}"), "ini")
+	assert.EqualError(t, err, `<reader>: failed to parse config file: unknown config format "ini", expected yaml, json or toml`)
+}
+
+func TestLoadConfigMixedFormats(t *testing.T) {
+	t.Setenv(AppEnvVar, "prod")
+	t.Setenv("BACKUPS", "9")
+	dir := writeConfigFiles(t, map[string]string{
+		"common.yaml": "logger:\n  max_backups: 3\n  compress: false\n",
+		"log.toml": `
+include = ["common.yaml"]
+
+[logger]
+filename = "$DIR/app.log"
+max_backups = "${BACKUPS}"
+`,
+		"log.prod.toml": `
+[logger]
+level = "error"
+`,
+	})
+	path := filepath.Join(dir, "log.toml")
+
+	config, sources, err := loadConfig(path, FormatAuto)
+	require.NoError(t, err)
+	assert.Equal(t, LogLevelError, config.Level)
+	// the placeholder string takes the type of its value
+	assert.Equal(t, Ptr(9), config.MaxBackups)
+	assert.Equal(t, Ptr(false), config.Compress)
+
+	assert.Equal(t, path+":6 (${BACKUPS})", sources["max_backups"].String())
+	assert.Equal(t, filepath.Join(dir, "log.prod.toml")+":3", sources["level"].String())
+	assert.Equal(t, filepath.Join(dir, "common.yaml")+":3", sources["compress"].String())
+}
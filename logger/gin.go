@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// ginComponentKey marks entries written on behalf of gin, since the default
+	// encoder does not output logger names
+	ginComponentKey = "component"
+	ginComponent    = "gin"
+)
+
+var (
+	// [GIN-debug] GET    /ping                     --> main.handler (3 handlers)
+	ginRouteRe = regexp.MustCompile(`^(\S+)\s+(\S+)\s+--> (\S+) \((\d+) handlers\)$`)
+	// [GIN] 2025/06/08 - 18:35:28 | 200 |    1.2345ms |       127.0.0.1 | GET      "/ping"
+	// followed by the errors of the request, if any, on the next lines:
+	// Error #01: something failed
+	ginAccessRe = regexp.MustCompile(`(?s)^\S+ - \S+ \|\s*(\d+) \|\s*(\S+) \|\s*(\S*) \|\s*(\S+)\s+"((?:[^"\\]|\\.)*)"(?:\n(.*))?$`)
+
+	ginWritersMu sync.Mutex
+)
+
+// InstallGinWriters replaces gin.DefaultWriter, gin.DefaultErrorWriter and
+// gin.DebugPrintRouteFunc so that gin's debug, route, access and error lines are
+// written to the global logger as structured entries. Route registrations get
+// method, path, handler and handlers fields, access lines get status, latency,
+// client_ip, method and path, and errors with the errors gin appends to them.
+//
+// gin captures the writers when creating middlewares such as gin.Logger and
+// gin.Recovery, so this should be called before building the engine.
+// The returned function restores the previous writers.
+func InstallGinWriters() (restore func()) {
+	return installGinWriters(GetLogger())
+}
+
+func installGinWriters(l *Logger) func() {
+	ginWritersMu.Lock()
+	defer ginWritersMu.Unlock()
+
+	prevWriter, prevErrorWriter, prevRouteFunc := gin.DefaultWriter, gin.DefaultErrorWriter, gin.DebugPrintRouteFunc
+
+	zl := l.logger.With(zap.String(ginComponentKey, ginComponent)).WithOptions(zap.WithCaller(false))
+	gin.DefaultWriter = &ginWriter{logger: zl, level: zapcore.InfoLevel}
+	gin.DefaultErrorWriter = &ginWriter{logger: zl, level: zapcore.ErrorLevel}
+	gin.DebugPrintRouteFunc = func(httpMethod, absolutePath, handlerName string, nuHandlers int) {
+		zl.Debug("route registered", ginRouteFields(httpMethod, absolutePath, handlerName, nuHandlers)...)
+	}
+
+	return func() {
+		ginWritersMu.Lock()
+		defer ginWritersMu.Unlock()
+		gin.DefaultWriter, gin.DefaultErrorWriter, gin.DebugPrintRouteFunc = prevWriter, prevErrorWriter, prevRouteFunc
+	}
+}
+
+// ginWriter parses the text gin writes and logs it as structured entries.
+// gin emits one complete message per Write call.
+type ginWriter struct {
+	logger *zap.Logger
+	level  zapcore.Level // level of lines that are not recognized
+}
+
+var _ io.Writer = (*ginWriter)(nil)
+
+func (w *ginWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	if msg == "" {
+		return len(p), nil
+	}
+	level, msg, fields := parseGinLine(msg, w.level)
+	if ce := w.logger.Check(level, msg); ce != nil {
+		ce.Write(fields...)
+	}
+	return len(p), nil
+}
+
+// parseGinLine turns one gin message into a level, message and fields.
+func parseGinLine(line string, level zapcore.Level) (zapcore.Level, string, []zap.Field) {
+	switch {
+	case strings.HasPrefix(line, "[GIN-debug] "):
+		line = strings.TrimPrefix(line, "[GIN-debug] ")
+		switch {
+		case strings.HasPrefix(line, "[WARNING] "):
+			return zapcore.WarnLevel, strings.TrimSpace(strings.TrimPrefix(line, "[WARNING] ")), nil
+		case strings.HasPrefix(line, "[ERROR] "):
+			return zapcore.ErrorLevel, strings.TrimSpace(strings.TrimPrefix(line, "[ERROR] ")), nil
+		}
+		if m := ginRouteRe.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[4])
+			return zapcore.DebugLevel, "route registered", ginRouteFields(m[1], m[2], m[3], n)
+		}
+		return zapcore.DebugLevel, strings.TrimSpace(line), nil
+
+	case strings.HasPrefix(line, "[GIN] "):
+		m := ginAccessRe.FindStringSubmatch(strings.TrimPrefix(line, "[GIN] "))
+		if m == nil {
+			break
+		}
+		status, _ := strconv.Atoi(m[1])
+		fields := []zap.Field{
+			zap.Int("status", status),
+			zap.String("method", m[4]),
+			zap.String("path", m[5]),
+			zap.String("client_ip", m[3]),
+		}
+		if latency, err := time.ParseDuration(m[2]); err == nil {
+			fields = append(fields, zap.Duration("latency", latency))
+		}
+		if errs := strings.TrimSpace(m[6]); errs != "" {
+			fields = append(fields, zap.String("errors", errs))
+		}
+		return zapcore.InfoLevel, "request", fields
+	}
+	return level, line, nil
+}
+
+func ginRouteFields(method, path, handler string, handlers int) []zap.Field {
+	return []zap.Field{
+		zap.String("method", method),
+		zap.String("path", path),
+		zap.String("handler", handler),
+		zap.Int("handlers", handlers),
+	}
+}
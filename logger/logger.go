@@ -2,15 +2,17 @@ package logger
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 const (
@@ -19,27 +21,22 @@ const (
 	timeKey       = "time"
 	levelKey      = "level"
 	messageKey    = "msg"
+	nameKey       = "logger"
 	callerKey     = "caller"
 	stacktraceKey = "stacktrace"
 
 	// log buffer size
-	bufferSize = 256 * 1024
+	bufferSize = 256 * KiB
 
 	// log file backup config
 	maxBackups = 5
-	maxAge     = 30
-	maxSize    = 100
+	maxAge     = 30 * Day
+	maxSize    = 100 * MiB
 )
 
 var (
 	once   sync.Once
 	logger *Logger
-	// Add a pool for Logger instances
-	loggerPool = sync.Pool{
-		New: func() any {
-			return &Logger{}
-		},
-	}
 )
 
 type loggerKey struct{}
@@ -49,43 +46,97 @@ func NewContextWithValue(ctx context.Context, l *Logger) context.Context {
 	return context.WithValue(ctx, loggerKey{}, l)
 }
 
-// FromContext returns the logger from the context.
-// If no logger is found, it returns the global default logger.
+// FromContext returns the logger from the context, with the fields stored by
+// StoreFieldsInContext merged in. If no logger is found, the global default
+// logger is used. When the logger and the context carry the same key, the
+// context value wins; see mergeFields for the exact rules.
 func FromContext(ctx context.Context) *Logger {
-	if l, ok := ctx.Value(loggerKey{}).(*Logger); ok {
-		return l
+	l, ok := ctx.Value(loggerKey{}).(*Logger)
+	if !ok {
+		l = GetLogger()
 	}
-	return GetLogger()
+	return l.WithContext(ctx)
+}
+
+// DebugCtx logs a debug message with the logger and fields carried by ctx.
+func DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	FromContext(ctx).logger.Debug(msg, fields...)
+}
+
+// InfoCtx logs an info message with the logger and fields carried by ctx.
+func InfoCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	FromContext(ctx).logger.Info(msg, fields...)
+}
+
+// WarnCtx logs a warning message with the logger and fields carried by ctx.
+func WarnCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	FromContext(ctx).logger.Warn(msg, fields...)
+}
+
+// ErrorCtx logs an error message with the logger and fields carried by ctx.
+func ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	FromContext(ctx).logger.Error(msg, fields...)
 }
 
 // Config log config
+//
+// The pointer fields tell an explicit zero or false apart from an unset value:
+// nil takes the default, while Ptr(0) keeps every backup for MaxBackups or
+// turns buffering off for BufferSize.
 type Config struct {
-	Level              LogLevel `json:"level" yaml:"level"`                               // log level: debug, info, warn, error, panic, fatal
-	Filename           string   `json:"filename" yaml:"filename"`                         // log file path
-	ErrorFilename      string   `json:"error_filename" yaml:"error_filename"`             // error log file path, if empty, use main log file
-	TimeFormat         string   `json:"time_format" yaml:"time_format"`                   // time format
-	MaxSize            int      `json:"max_size" yaml:"max_size"`                         // max size of log file(MB)
-	MaxBackups         int      `json:"max_backups" yaml:"max_backups"`                   // max number of log file backups
-	MaxAge             int      `json:"max_age" yaml:"max_age"`                           // max number of days to keep log files
-	BufferSize         int      `json:"buffer_size" yaml:"buffer_size"`                   // output buffer size
-	Compress           bool     `json:"compress" yaml:"compress"`                         // compress old log files
-	Console            bool     `json:"console" yaml:"console"`                           // output log to console
-	DisableCaller      bool     `json:"disable_caller" yaml:"disable_caller"`             // disable caller info
-	DisableStacktrace  bool     `json:"disable_stacktrace" yaml:"disable_stacktrace"`     // disable stacktrace
-	EnableAsync        bool     `json:"enable_async" yaml:"enable_async"`                 // enable async logging
-	AsyncBufferSize    int      `json:"async_buffer_size" yaml:"async_buffer_size"`       // async buffer size
-	AsyncFlushInterval int      `json:"async_flush_interval" yaml:"async_flush_interval"` // async flush interval in milliseconds
-}
-
-// Logger
+	Level              LogLevel  `json:"level" yaml:"level"`                               // log level: debug, info, warn, error, panic, fatal
+	Filename           string    `json:"filename" yaml:"filename"`                         // log file path
+	ErrorFilename      string    `json:"error_filename" yaml:"error_filename"`             // error log file path, if empty, use main log file
+	TimeFormat         string    `json:"time_format" yaml:"time_format"`                   // time format
+	MaxSize            *FileSize `json:"max_size" yaml:"max_size"`                         // max size of log file, 100MiB, bare numbers in MB
+	MaxBackups         *int      `json:"max_backups" yaml:"max_backups"`                   // max number of log file backups, 0 keeps them all
+	MaxAge             *Age      `json:"max_age" yaml:"max_age"`                           // how long to keep log files, 30d, bare numbers in days, 0 keeps them forever
+	BufferSize         *ByteSize `json:"buffer_size" yaml:"buffer_size"`                   // output buffer size, 256KiB, 0 disables buffering
+	Compress           *bool     `json:"compress" yaml:"compress"`                         // compress old log files
+	Console            *bool     `json:"console" yaml:"console"`                           // output log to console
+	DisableCaller      bool      `json:"disable_caller" yaml:"disable_caller"`             // disable caller info
+	DisableStacktrace  bool      `json:"disable_stacktrace" yaml:"disable_stacktrace"`     // disable stacktrace
+	EnableAsync        bool      `json:"enable_async" yaml:"enable_async"`                 // enable async logging
+	AsyncBufferSize    *ByteSize `json:"async_buffer_size" yaml:"async_buffer_size"`       // async buffer size, 256KiB
+	AsyncFlushInterval *Duration `json:"async_flush_interval" yaml:"async_flush_interval"` // async flush interval, 1s, bare numbers in milliseconds
+
+	Fields   map[string]string `json:"fields,omitempty" yaml:"fields,omitempty"` // fields added to every entry, such as service and version
+	Metadata MetadataConfig    `json:"metadata" yaml:"metadata"`                 // metadata detected at startup and added to every entry
+	Encoder  EncodingConfig    `json:"encoder" yaml:"encoder"`                   // keys and formats of the log file entries
+}
+
+// Ptr returns a pointer to v, to set the optional fields of Config in code.
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// Logger writes structured logs to the configured outputs.
+//
+// A Logger returned by NewLogger is a root logger: it owns the log files and
+// buffers and is the only one allowed to release them. WithFields, WithLevel,
+// WithContext and the other With methods return derived loggers, which are
+// immutable views sharing the root's outputs. Derived loggers are cheap, may be
+// used from any goroutine and may be kept for as long as needed, but they
+// become unusable once the root is closed. Closing a derived logger does
+// nothing.
 type Logger struct {
 	config *Config
-
-	fileCore      zapcore.Core
-	consoleCore   zapcore.Core
-	errorCore     zapcore.Core
-	logger        *zap.Logger
-	sugaredLogger *zap.SugaredLogger
+	root   *Logger // the logger created by NewLogger, owner of the outputs
+
+	// set on the root only
+	writers   []*sharedWriter
+	closers   []func() error // release the owned outputs, called in reverse order
+	closeOnce sync.Once
+	closeDone chan struct{}
+	closeErr  error
+
+	fileCore    zapcore.Core
+	consoleCore zapcore.Core
+	errorCore   zapcore.Core
+	base        *zap.Logger // logger without the fields added by WithFields
+	fields      []zap.Field // fields added by WithFields, logger is base.With(fields...)
+	logger      *zap.Logger
+	sugar       atomic.Pointer[zap.SugaredLogger] // created on first use
 }
 
 func InitLogger(config *Config) (err error) {
@@ -107,86 +158,58 @@ func GetLogger() *Logger {
 // NewLogger create a new logger
 func NewLogger(config *Config) (*Logger, error) {
 	c := mergeConfigWithDefault(config)
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
 	level, err := zapcore.ParseLevel(c.Level.String())
 	if err != nil {
 		return nil, err
 	}
 
-	// optimized encoder config
-	encoderConfig := zapcore.EncoderConfig{
-		TimeKey:        timeKey,
-		LevelKey:       levelKey,
-		MessageKey:     messageKey,
-		LineEnding:     zapcore.DefaultLineEnding,
-		EncodeLevel:    zapcore.LowercaseLevelEncoder,
-		EncodeTime:     zapcore.TimeEncoderOfLayout(c.TimeFormat),
-		EncodeDuration: zapcore.SecondsDurationEncoder,
-	}
-
-	if !c.DisableCaller {
-		encoderConfig.CallerKey = callerKey
-		encoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
-	}
-	if !c.DisableStacktrace {
-		encoderConfig.StacktraceKey = stacktraceKey
-	}
+	encoderConfig := newEncoderConfig(c)
+	encoder := newEncoder(c, zapcore.NewJSONEncoder(encoderConfig))
+	// added to the cores themselves, so every derived logger keeps them
+	static := c.staticFields()
 
 	l := &Logger{
 		config: c,
 	}
+	l.root = l
 
 	var cores []zapcore.Core
 
 	// main log file core
 	if c.Filename != "" {
-		fileWriteSyncer, err := createLogWriter(c.Filename, c)
+		fileWriteSyncer, err := l.createLogWriter(c.Filename, c)
 		if err != nil {
+			_ = l.release()
 			return nil, err
 		}
-		if c.EnableAsync {
-			fileWriteSyncer = &zapcore.BufferedWriteSyncer{
-				WS:   fileWriteSyncer,
-				Size: c.AsyncBufferSize,
-			}
-		}
-		l.fileCore = createLogCore(fileWriteSyncer, encoderConfig, level)
+		l.fileCore = createLogCore(l.async(fileWriteSyncer), encoder, level).With(static)
 		cores = append(cores, l.fileCore)
 	}
 
 	// error log file core
 	if c.ErrorFilename != "" {
-		errorWriteSyncer, err := createLogWriter(c.ErrorFilename, c)
+		errorWriteSyncer, err := l.createLogWriter(c.ErrorFilename, c)
 		if err != nil {
+			_ = l.release()
 			return nil, err
 		}
-		if c.EnableAsync {
-			errorWriteSyncer = &zapcore.BufferedWriteSyncer{
-				WS:   errorWriteSyncer,
-				Size: c.AsyncBufferSize,
-			}
-		}
-		l.errorCore = createLogCore(errorWriteSyncer, encoderConfig, zapcore.ErrorLevel)
+		l.errorCore = createLogCore(l.async(errorWriteSyncer), encoder, zapcore.ErrorLevel).With(static)
 		cores = append(cores, l.errorCore)
 	}
 
 	// console output core
-	if c.Console {
+	if *c.Console {
 		consoleEncoderConfig := encoderConfig
 		consoleEncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
 
-		consoleWriteSyncer := zapcore.AddSync(os.Stdout)
-		if c.EnableAsync {
-			consoleWriteSyncer = &zapcore.BufferedWriteSyncer{
-				WS:   consoleWriteSyncer,
-				Size: c.AsyncBufferSize,
-			}
-		}
-
 		l.consoleCore = zapcore.NewCore(
-			zapcore.NewConsoleEncoder(consoleEncoderConfig),
-			consoleWriteSyncer,
+			newEncoder(c, zapcore.NewConsoleEncoder(consoleEncoderConfig)),
+			l.async(zapcore.AddSync(os.Stdout)),
 			level,
-		)
+		).With(static)
 		cores = append(cores, l.consoleCore)
 	}
 
@@ -206,12 +229,75 @@ func NewLogger(config *Config) (*Logger, error) {
 		opts = append(opts, zap.AddStacktrace(zapcore.ErrorLevel))
 	}
 
-	l.logger = zap.New(core, opts...)
-	l.sugaredLogger = l.logger.Sugar()
+	l.base = zap.New(core, opts...)
+	l.logger = l.base
 
 	return l, nil
 }
 
+// NewEncoderConfig returns the encoder config NewLogger uses for the log files
+// described by config, with unset values defaulted. config is not modified.
+func NewEncoderConfig(config *Config) zapcore.EncoderConfig {
+	return newEncoderConfig(mergeConfigWithDefault(config))
+}
+
+// newEncoderConfig returns the encoder config of c, which must have its
+// defaults set. The encoders of unknown names, which only Validate reports,
+// fall back to the default ones.
+func newEncoderConfig(c *Config) zapcore.EncoderConfig {
+	e := c.Encoder
+	def := defaultConfig().Encoder
+	encodeTime := lookupEncoder(timeEncoders, e.Time, "")
+	if encodeTime == nil {
+		// layout, the default, is not in timeEncoders
+		encodeTime = zapcore.TimeEncoderOfLayout(c.TimeFormat)
+	}
+	// optimized encoder config
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        *e.TimeKey,
+		LevelKey:       *e.LevelKey,
+		NameKey:        *e.NameKey,
+		MessageKey:     *e.MessageKey,
+		FunctionKey:    *e.FunctionKey,
+		LineEnding:     lookupEncoder(lineEndings, e.LineEnding, def.LineEnding),
+		EncodeLevel:    lookupEncoder(levelEncoders, e.Level, def.Level),
+		EncodeTime:     encodeTime,
+		EncodeDuration: lookupEncoder(durationEncoders, e.Duration, def.Duration),
+		EncodeName:     lookupEncoder(nameEncoders, e.Name, def.Name),
+	}
+
+	if !c.DisableCaller && !callerObjectPreset(e.Preset) {
+		encoderConfig.CallerKey = *e.CallerKey
+		encoderConfig.EncodeCaller = lookupEncoder(callerEncoders, e.Caller, def.Caller)
+	}
+	if !c.DisableStacktrace {
+		encoderConfig.StacktraceKey = *e.StacktraceKey
+	}
+	return encoderConfig
+}
+
+// NewLoggerWithCore creates a root logger writing to core instead of the files
+// and console described by a Config, for example to capture entries in tests
+// with zaptest/observer. The caller is added like NewLogger does, opts are
+// applied after it. WithLevel lowers the level of core, and Close only flushes
+// it since the logger does not own it.
+func NewLoggerWithCore(core zapcore.Core, opts ...zap.Option) *Logger {
+	opts = append([]zap.Option{zap.AddCaller(), zap.AddCallerSkip(callerSkip)}, opts...)
+	base := zap.New(core, opts...)
+	l := newRootLogger(mergeConfigWithDefault(nil), base)
+	// the core with the fields and wrappers from opts, so WithLevel keeps them
+	l.fileCore = base.Core()
+	return l
+}
+
+// newRootLogger returns a root logger writing through base only, without any
+// output of its own.
+func newRootLogger(config *Config, base *zap.Logger) *Logger {
+	l := &Logger{config: config, base: base, logger: base}
+	l.root = l
+	return l
+}
+
 // defaultConfig return default config
 func defaultConfig() *Config {
 	return &Config{
@@ -219,50 +305,101 @@ func defaultConfig() *Config {
 		Filename:           filepath.Join("logs", "app.log"), // 默认 logs 目录
 		ErrorFilename:      filepath.Join("logs", "error.log"),
 		TimeFormat:         timeFormat,
-		MaxSize:            maxSize,
-		MaxBackups:         maxBackups,
-		MaxAge:             maxAge,
-		BufferSize:         bufferSize,
-		Compress:           true,
-		Console:            true,
+		MaxSize:            Ptr[FileSize](maxSize),
+		MaxBackups:         Ptr(maxBackups),
+		MaxAge:             Ptr(Age(maxAge)),
+		BufferSize:         Ptr[ByteSize](bufferSize),
+		Compress:           Ptr(true),
+		Console:            Ptr(true),
 		DisableCaller:      false,
 		DisableStacktrace:  false,
 		EnableAsync:        false,
-		AsyncBufferSize:    256 * 1024,
-		AsyncFlushInterval: 1000,
+		AsyncBufferSize:    Ptr[ByteSize](256 * KiB),
+		AsyncFlushInterval: Ptr(Duration(time.Second)),
+		Metadata: MetadataConfig{
+			Hostname:    Ptr(false),
+			PID:         Ptr(false),
+			GoVersion:   Ptr(false),
+			VCSRevision: Ptr(false),
+			Kubernetes:  Ptr(false),
+		},
+		Encoder: EncodingConfig{
+			MessageKey:    Ptr(messageKey),
+			LevelKey:      Ptr(levelKey),
+			TimeKey:       Ptr(timeKey),
+			NameKey:       Ptr(nameKey),
+			CallerKey:     Ptr(callerKey),
+			FunctionKey:   Ptr(""),
+			StacktraceKey: Ptr(stacktraceKey),
+			Level:         "lowercase",
+			Time:          layoutTimeEncoder,
+			Duration:      "seconds",
+			Caller:        "short",
+			Name:          "full",
+			LineEnding:    "lf",
+		},
 	}
 }
 
+// mergeConfigWithDefault returns cfg with its unset values defaulted, every
+// pointer field of the result is set. The encoder preset of cfg applies over
+// the defaults. cfg is not modified.
 func mergeConfigWithDefault(cfg *Config) *Config {
-	def := defaultConfig()
-	if cfg == nil {
-		return def
-	}
-	if cfg.Level == "" {
-		cfg.Level = def.Level
-	}
-	if cfg.Filename == "" {
-		cfg.Filename = def.Filename
-	}
-	if cfg.ErrorFilename == "" {
-		cfg.ErrorFilename = def.ErrorFilename
-	}
-	if cfg.TimeFormat == "" {
-		cfg.TimeFormat = def.TimeFormat
-	}
-	if cfg.MaxSize == 0 {
-		cfg.MaxSize = def.MaxSize
-	}
-	if cfg.MaxBackups == 0 {
-		cfg.MaxBackups = def.MaxBackups
+	base := defaultConfig()
+	if cfg != nil {
+		if preset, ok := presetEncodings[cfg.Encoder.Preset]; ok {
+			pickEncoding(&base.Encoder, preset)
+		}
 	}
-	if cfg.MaxAge == 0 {
-		cfg.MaxAge = def.MaxAge
+	return mergeConfig(base, cfg)
+}
+
+// mergeConfig returns a new Config with the values set in over and the values
+// of base for the others. A value is set when it is non-nil for the pointer
+// fields, non-empty for strings and true for the other bools, so an explicit
+// 0 or false in over wins over base. Fields are merged key by key. Either
+// argument may be nil, neither is modified nor shares memory with the result.
+func mergeConfig(base, over *Config) *Config {
+	merged := &Config{}
+	for _, cfg := range []*Config{base, over} {
+		if cfg == nil {
+			continue
+		}
+		pick(&merged.Level, cfg.Level)
+		pick(&merged.Filename, cfg.Filename)
+		pick(&merged.ErrorFilename, cfg.ErrorFilename)
+		pick(&merged.TimeFormat, cfg.TimeFormat)
+		pickPtr(&merged.MaxSize, cfg.MaxSize)
+		pickPtr(&merged.MaxBackups, cfg.MaxBackups)
+		pickPtr(&merged.MaxAge, cfg.MaxAge)
+		pickPtr(&merged.BufferSize, cfg.BufferSize)
+		pickPtr(&merged.Compress, cfg.Compress)
+		pickPtr(&merged.Console, cfg.Console)
+		pick(&merged.DisableCaller, cfg.DisableCaller)
+		pick(&merged.DisableStacktrace, cfg.DisableStacktrace)
+		pick(&merged.EnableAsync, cfg.EnableAsync)
+		pickPtr(&merged.AsyncBufferSize, cfg.AsyncBufferSize)
+		pickPtr(&merged.AsyncFlushInterval, cfg.AsyncFlushInterval)
+		pickFields(&merged.Fields, cfg.Fields)
+		pickMetadata(&merged.Metadata, cfg.Metadata)
+		pickEncoding(&merged.Encoder, cfg.Encoder)
+	}
+	return merged
+}
+
+// pick sets dst to v unless v is the zero value.
+func pick[T comparable](dst *T, v T) {
+	var zero T
+	if v != zero {
+		*dst = v
 	}
-	if cfg.BufferSize == 0 {
-		cfg.BufferSize = def.BufferSize
+}
+
+// pickPtr sets dst to a copy of *v unless v is nil.
+func pickPtr[T any](dst **T, v *T) {
+	if v != nil {
+		*dst = Ptr(*v)
 	}
-	return cfg
 }
 
 // GetLogger get the logger
@@ -270,8 +407,14 @@ func (l *Logger) GetLogger() *zap.Logger {
 	return l.logger
 }
 
+// GetSugaredLogger returns the sugared form of the logger, created on first use.
 func (l *Logger) GetSugaredLogger() *zap.SugaredLogger {
-	return l.sugaredLogger
+	if s := l.sugar.Load(); s != nil {
+		return s
+	}
+	// concurrent callers may both build one, only the first is kept
+	l.sugar.CompareAndSwap(nil, l.logger.Sugar())
+	return l.sugar.Load()
 }
 
 // WithFields add fields to logger
@@ -280,16 +423,49 @@ func (l *Logger) WithFields(fields ...zap.Field) *Logger {
 		return l
 	}
 
-	newLogger := l.logger.With(fields...)
-	// Get a Logger instance from pool
-	newL := loggerPool.Get().(*Logger)
-	newL.logger = newLogger
-	newL.sugaredLogger = newLogger.Sugar()
-	newL.config = l.config
-	newL.fileCore = l.fileCore
-	newL.consoleCore = l.consoleCore
-	newL.errorCore = l.errorCore
-	return newL
+	// full slice expression forces a copy so siblings never share the backing array
+	return l.derive(l.base, append(l.fields[:len(l.fields):len(l.fields)], fields...), l.logger.With(fields...))
+}
+
+// WithLevel returns a logger whose main log file and console outputs accept
+// entries from level upwards, regardless of the configured level. The error log
+// file keeps its own level. Fields added by WithFields are preserved, and l
+// itself is not affected, so it is safe to lower the level for a single request.
+func (l *Logger) WithLevel(level zapcore.Level) *Logger {
+	var cores []zapcore.Core
+	if l.fileCore != nil {
+		cores = append(cores, &levelCore{Core: l.fileCore, level: level})
+	}
+	if l.consoleCore != nil {
+		cores = append(cores, &levelCore{Core: l.consoleCore, level: level})
+	}
+	if l.errorCore != nil {
+		cores = append(cores, l.errorCore)
+	}
+	if len(cores) == 0 {
+		return l
+	}
+
+	core := zapcore.NewTee(cores...)
+	base := l.base.WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core {
+		return core
+	}))
+	return l.derive(base, l.fields, base.With(l.fields...))
+}
+
+// derive returns a new logger sharing l's root and outputs with the given base
+// and fields. logger must be base.With(fields...).
+func (l *Logger) derive(base *zap.Logger, fields []zap.Field, logger *zap.Logger) *Logger {
+	return &Logger{
+		config:      l.config,
+		root:        l.root,
+		fileCore:    l.fileCore,
+		consoleCore: l.consoleCore,
+		errorCore:   l.errorCore,
+		base:        base,
+		fields:      fields,
+		logger:      logger,
+	}
 }
 
 // WithFieldsMap add fields from map to logger
@@ -306,14 +482,102 @@ func (l *Logger) WithFieldsMap(fields map[string]any) *Logger {
 	return l.WithFields(zapFields...)
 }
 
-// WithContext returns a logger with fields extracted from context.
+// WithContext returns a logger with fields extracted from context merged into
+// the logger's own fields, the context winning on duplicate keys.
 // If no fields are found, returns the original logger.
 func (l *Logger) WithContext(ctx context.Context) *Logger {
-	fields := GetFieldsFromContext(ctx)
-	if len(fields) == 0 {
+	if ctx == nil {
+		return l
+	}
+	n := fieldNodeOf(ctx)
+	if n == nil {
 		return l
 	}
-	return l.WithFields(fields...)
+	// the logger derived last from the fields of ctx is kept on them, so that
+	// logging through FromContext again with the same context is free
+	c := n.cache.Load()
+	if c != nil && c.from == l {
+		return c.logger
+	}
+	fields := n.flatten()
+	merged := mergeFields(l.fields, fields)
+	derived := l.derive(l.base, merged, l.base.With(merged...))
+	n.cache.Store(&fieldCache{flat: fields, from: l, logger: derived})
+	return derived
+}
+
+// mergeFields appends extra to fields and drops every field whose key appears
+// again later, so the last value of a key wins and keeps the position of that
+// last occurrence. Keys after a zap.Namespace live in that namespace and are
+// only compared with keys of the same namespace. The inputs are not modified.
+func mergeFields(fields, extra []zap.Field) []zap.Field {
+	all := make([]zap.Field, 0, len(fields)+len(extra))
+	all = append(all, fields...)
+	all = append(all, extra...)
+	return dedupeFields(all)
+}
+
+// dedupeFieldsMapThreshold is the size above which dedupeFields switches from
+// pairwise comparison to a map.
+const dedupeFieldsMapThreshold = 64
+
+// dedupeFields removes, in place, the fields whose key is repeated later in the
+// same namespace, see mergeFields.
+func dedupeFields(fields []zap.Field) []zap.Field {
+	if len(fields) > dedupeFieldsMapThreshold {
+		return dedupeFieldsMap(fields)
+	}
+
+	out := fields[:0]
+	for i, f := range fields {
+		if f.Type != zapcore.NamespaceType && keyRepeated(fields, i) {
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// keyRepeated reports whether fields[i].Key appears again before the next
+// namespace; fields after a namespace are nested and cannot collide.
+func keyRepeated(fields []zap.Field, i int) bool {
+	for _, f := range fields[i+1:] {
+		if f.Type == zapcore.NamespaceType {
+			return false
+		}
+		if f.Key == fields[i].Key {
+			return true
+		}
+	}
+	return false
+}
+
+func dedupeFieldsMap(fields []zap.Field) []zap.Field {
+	type scopedKey struct {
+		scope int
+		key   string
+	}
+	last := make(map[scopedKey]int, len(fields))
+	scope := 0
+	for i, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			scope++
+			continue
+		}
+		last[scopedKey{scope, f.Key}] = i
+	}
+
+	out := fields[:0]
+	scope = 0
+	for i, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			scope++
+		} else if last[scopedKey{scope, f.Key}] != i {
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
 }
 
 // Info log info
@@ -338,79 +602,185 @@ func (l *Logger) Error(msg string, fields ...zap.Field) {
 
 // Infof log info with format, use sugared logger
 func (l *Logger) Infof(template string, args ...any) {
-	l.sugaredLogger.Infof(template, args...)
+	l.GetSugaredLogger().Infof(template, args...)
 }
 
 // Debugf log debug with format, use sugared logger
 func (l *Logger) Debugf(template string, args ...any) {
-	l.sugaredLogger.Debugf(template, args...)
+	l.GetSugaredLogger().Debugf(template, args...)
 }
 
 // Warnf log warn with format, use sugared logger
 func (l *Logger) Warnf(template string, args ...any) {
-	l.sugaredLogger.Warnf(template, args...)
+	l.GetSugaredLogger().Warnf(template, args...)
 }
 
 // Errorf log error with format, use sugared logger
 func (l *Logger) Errorf(template string, args ...any) {
-	l.sugaredLogger.Errorf(template, args...)
+	l.GetSugaredLogger().Errorf(template, args...)
 }
 
-// Sync sync the logger
+// Sync sync the logger, errors from syncing a console that does not support it
+// are ignored like Close does.
 func (l *Logger) Sync() error {
-	return l.logger.Sync()
+	return dropIgnorableSyncErrors(l.logger.Sync())
 }
 
-// Close sync and close the logger
-func (l *Logger) Close() error {
-	err := l.Sync()
-	// Put the Logger instance back to pool
-	loggerPool.Put(l)
-	// Ignore sync errors for os.Stdout and os.Stderr
-	if err != nil && (strings.Contains(err.Error(), "invalid argument") || strings.Contains(err.Error(), "/dev/stdout")) {
+// Close flushes buffered entries, stops the background flush goroutines and
+// closes the log files. It waits until everything is released or ctx is done,
+// in which case ctx.Err() is returned and the release finishes in the
+// background. Close can be called any number of times, later calls report the
+// result of the first one. Failures of the individual outputs are combined with
+// multierr; errors from syncing a console that does not support it are ignored.
+//
+// Only a root logger is closed, calling Close on a derived logger does nothing,
+// so handing a derived logger to code that closes it never affects the outputs
+// shared with other loggers. Nothing may be logged through the root or its
+// derived loggers once Close was called.
+func (l *Logger) Close(ctx context.Context) error {
+	if l.root != l {
 		return nil
 	}
-	return err
+	l.closeOnce.Do(func() {
+		l.closeDone = make(chan struct{})
+		go func() {
+			defer close(l.closeDone)
+			l.closeErr = multierr.Append(
+				dropIgnorableSyncErrors(l.logger.Sync()),
+				dropIgnorableSyncErrors(l.release()),
+			)
+		}()
+	})
+
+	select {
+	case <-l.closeDone:
+		return l.closeErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-// createLogWriter create a log writer
-func createLogWriter(filename string, config *Config) (zapcore.WriteSyncer, error) {
+// Rotate flushes the logger and moves the current log files to backups, so
+// that new entries go to fresh files. Backups are kept according to the
+// rotation settings. Loggers writing to the same files see the rotation too.
+// Calling Rotate on a derived logger rotates the files of its root.
+func (l *Logger) Rotate() error {
+	r := l.root
+	errs := dropIgnorableSyncErrors(r.logger.Sync())
+	for _, w := range r.writers {
+		errs = multierr.Append(errs, w.Rotate())
+	}
+	return errs
+}
+
+// dropIgnorableSyncErrors removes the errors of outputs that cannot sync from
+// the combined error err.
+func dropIgnorableSyncErrors(err error) error {
+	var errs error
+	for _, err := range multierr.Errors(err) {
+		if !isIgnorableSyncError(err) {
+			errs = multierr.Append(errs, err)
+		}
+	}
+	return errs
+}
+
+// isIgnorableSyncError reports whether err comes from syncing a terminal or a
+// pipe such as os.Stdout, which fails with EINVAL on Linux and ENOTTY on macOS.
+func isIgnorableSyncError(err error) bool {
+	return errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTTY)
+}
+
+// release calls the registered closers, the last registered first.
+func (l *Logger) release() error {
+	var errs error
+	for i := len(l.closers) - 1; i >= 0; i-- {
+		errs = multierr.Append(errs, l.closers[i]())
+	}
+	l.closers = nil
+	return errs
+}
+
+// async wraps ws in a BufferedWriteSyncer when async logging is enabled. The
+// flush goroutine is stopped by Close.
+func (l *Logger) async(ws zapcore.WriteSyncer) zapcore.WriteSyncer {
+	c := l.config
+	if !c.EnableAsync {
+		return ws
+	}
+	buffered := &zapcore.BufferedWriteSyncer{
+		WS:            ws,
+		Size:          int(*c.AsyncBufferSize),
+		FlushInterval: time.Duration(*c.AsyncFlushInterval),
+	}
+	l.closers = append(l.closers, buffered.Stop)
+	return buffered
+}
+
+// createLogWriter create a log writer, the file is released by Close
+func (l *Logger) createLogWriter(filename string, config *Config) (zapcore.WriteSyncer, error) {
 	// ensure log directory exists
 	logDir := filepath.Dir(filename)
 	if err := os.MkdirAll(logDir, 0o755); err != nil {
 		return nil, err
 	}
 
-	// create log file writer
-	writer := &lumberjack.Logger{
-		Filename:   filename,
-		MaxSize:    config.MaxSize,
-		MaxBackups: config.MaxBackups,
-		MaxAge:     config.MaxAge,
-		Compress:   config.Compress,
-		LocalTime:  true,
+	// get the log file writer, shared with other loggers using the same file
+	writer, err := openWriter(filename, config)
+	if err != nil {
+		return nil, err
 	}
+	l.writers = append(l.writers, writer)
+	l.closers = append(l.closers, writer.release)
 
 	// use buffered writer to improve performance
-	if config.BufferSize > 0 {
+	if *config.BufferSize > 0 {
 		// Use a larger buffer size for better performance
-		bufferSize := config.BufferSize
+		bufferSize := int(*config.BufferSize)
 		if bufferSize < 4096 {
 			bufferSize = 4096 // Minimum buffer size
 		}
-		return &zapcore.BufferedWriteSyncer{
+		buffered := &zapcore.BufferedWriteSyncer{
 			WS:   zapcore.AddSync(writer),
 			Size: bufferSize,
-		}, nil
+		}
+		l.closers = append(l.closers, buffered.Stop)
+		return buffered, nil
 	}
 
 	return zapcore.AddSync(writer), nil
 }
 
+// levelCore overrides the level of the wrapped core.
+// zapcore cores only check the level in Check, so Write can be delegated as is.
+type levelCore struct {
+	zapcore.Core
+	level zapcore.LevelEnabler
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.level.Enabled(lvl)
+}
+
+func (c *levelCore) Level() zapcore.Level {
+	return zapcore.LevelOf(c.level)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
 // createLogCore create a log core
-func createLogCore(writer zapcore.WriteSyncer, encoderConfig zapcore.EncoderConfig, level zapcore.Level) zapcore.Core {
+func createLogCore(writer zapcore.WriteSyncer, encoder zapcore.Encoder, level zapcore.Level) zapcore.Core {
 	return zapcore.NewCore(
-		zapcore.NewJSONEncoder(encoderConfig),
+		encoder,
 		writer,
 		level,
 	)
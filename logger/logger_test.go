@@ -2,14 +2,33 @@ package logger
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
+// tempConfig returns the default config writing to a temp dir of t instead of
+// the logs dir of the package.
+func tempConfig(t *testing.T) *Config {
+	t.Helper()
+	dir := t.TempDir()
+	config := defaultConfig()
+	config.Filename = filepath.Join(dir, "app.log")
+	config.ErrorFilename = filepath.Join(dir, "error.log")
+	return config
+}
+
 func TestLoggerInitialization(t *testing.T) {
 	// Test default config
 	config := defaultConfig()
@@ -19,7 +38,7 @@ func TestLoggerInitialization(t *testing.T) {
 	assert.Equal(t, filepath.Join("logs", "error.log"), config.ErrorFilename)
 
 	// Test logger initialization
-	err := InitLogger(config)
+	err := InitLogger(tempConfig(t))
 	assert.NoError(t, err)
 
 	// Test getting logger
@@ -39,8 +58,7 @@ func TestLoggerInitialization(t *testing.T) {
 }
 
 func TestLoggerWithFields(t *testing.T) {
-	config := defaultConfig()
-	err := InitLogger(config)
+	err := InitLogger(tempConfig(t))
 	assert.NoError(t, err)
 
 	log := GetLogger()
@@ -63,8 +81,7 @@ func TestLoggerWithFields(t *testing.T) {
 }
 
 func TestLoggerContext(t *testing.T) {
-	config := defaultConfig()
-	err := InitLogger(config)
+	err := InitLogger(tempConfig(t))
 	assert.NoError(t, err)
 
 	log := GetLogger()
@@ -127,18 +144,93 @@ logger:
 }
 
 func TestLoggerClose(t *testing.T) {
-	config := defaultConfig()
-	err := InitLogger(config)
+	err := InitLogger(tempConfig(t))
 	assert.NoError(t, err)
 
 	log := GetLogger()
 	assert.NotNil(t, log)
 
 	// Test logger close
-	err = log.Close()
+	err = log.Close(context.Background())
 	assert.NoError(t, err)
 }
 
+func TestLoggerCloseReleasesFiles(t *testing.T) {
+	dir := t.TempDir()
+	log, err := NewLogger(&Config{
+		Filename:           filepath.Join(dir, "app.log"),
+		ErrorFilename:      filepath.Join(dir, "error.log"),
+		EnableAsync:        true,
+		AsyncFlushInterval: Ptr(Duration(10 * time.Millisecond)),
+	})
+	require.NoError(t, err)
+
+	log.Info("buffered message")
+	log.Error("buffered error")
+
+	// files are opened on the first flush
+	require.NoError(t, log.Sync())
+	// closing a derived logger releases nothing
+	assert.NoError(t, log.WithFields(zap.String("req", "a")).Close(context.Background()))
+	assert.NotZero(t, openFilesIn(t, dir))
+
+	assert.NoError(t, log.Close(context.Background()))
+	assert.Zero(t, openFilesIn(t, dir))
+	// idempotent
+	assert.NoError(t, log.Close(context.Background()))
+
+	appLog, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(appLog), "buffered message")
+	errorLog, err := os.ReadFile(filepath.Join(dir, "error.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(errorLog), "buffered error")
+}
+
+func TestLoggerCloseDeadline(t *testing.T) {
+	log := newRootLogger(&Config{}, zap.NewNop())
+	unblock := make(chan struct{})
+	log.closers = append(log.closers, func() error {
+		<-unblock
+		return errors.New("close failed")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, log.Close(ctx), context.DeadlineExceeded)
+
+	// the release goes on in the background, later calls wait for its result
+	close(unblock)
+	assert.EqualError(t, log.Close(context.Background()), "close failed")
+}
+
+func TestDropIgnorableSyncErrors(t *testing.T) {
+	stdout := &os.PathError{Op: "sync", Path: "/dev/stdout", Err: syscall.EINVAL}
+	tty := &os.PathError{Op: "sync", Path: "/dev/stderr", Err: syscall.ENOTTY}
+	disk := &os.PathError{Op: "write", Path: "app.log", Err: syscall.ENOSPC}
+
+	assert.NoError(t, dropIgnorableSyncErrors(nil))
+	assert.NoError(t, dropIgnorableSyncErrors(multierr.Combine(stdout, tty)))
+	assert.Equal(t, disk, dropIgnorableSyncErrors(multierr.Combine(stdout, disk, tty)))
+}
+
+// openFilesIn returns the number of files under dir opened by this process.
+func openFilesIn(t *testing.T, dir string) int {
+	t.Helper()
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("/proc/self/fd is not available")
+	}
+	n := 0
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join("/proc/self/fd", fd.Name()))
+		if err == nil && strings.HasPrefix(target, dir) {
+			n++
+		}
+	}
+	return n
+}
+
 func TestLogLevel(t *testing.T) {
 	// Test LogLevel string conversion
 	assert.Equal(t, "debug", LogLevelDebug.String())
@@ -148,3 +240,133 @@ func TestLogLevel(t *testing.T) {
 	assert.Equal(t, "panic", LogLevelPanic.String())
 	assert.Equal(t, "fatal", LogLevelFatal.String())
 }
+
+func TestLoggerWithLevel(t *testing.T) {
+	dir := t.TempDir()
+	log, err := NewLogger(&Config{
+		Level:         LogLevelInfo,
+		Filename:      filepath.Join(dir, "app.log"),
+		ErrorFilename: filepath.Join(dir, "error.log"),
+	})
+	assert.NoError(t, err)
+
+	debugLog := log.WithFields(zap.String("req", "a")).WithLevel(zapcore.DebugLevel)
+	log.Debug("hidden debug message")
+	debugLog.Debug("visible debug message")
+	debugLog.Error("error message")
+	assert.NoError(t, log.Sync())
+	assert.NoError(t, debugLog.Sync())
+
+	appLog, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(appLog), "hidden debug message")
+	assert.Contains(t, string(appLog), `"msg":"visible debug message","req":"a"`)
+
+	// error log keeps its own level
+	errorLog, err := os.ReadFile(filepath.Join(dir, "error.log"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(errorLog), "visible debug message")
+}
+
+func TestNewLoggerWithCore(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	log := NewLoggerWithCore(core, zap.Fields(zap.String("service", "demo")))
+
+	log.Debug("hidden debug message")
+	log.WithFields(zap.String("req", "a")).WithLevel(zapcore.DebugLevel).Debug("visible debug message")
+	assert.NoError(t, log.Close(context.Background()))
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "visible debug message", entries[0].Message)
+	assert.Equal(t, map[string]any{"service": "demo", "req": "a"}, entries[0].ContextMap())
+	assert.Equal(t, "logger_test.go", filepath.Base(entries[0].Caller.File))
+}
+
+func TestMergeConfig(t *testing.T) {
+	def := defaultConfig()
+	tests := []struct {
+		name string
+		cfg  *Config
+		want func(c *Config)
+	}{
+		{name: "nil", cfg: nil, want: func(*Config) {}},
+		{name: "empty", cfg: &Config{}, want: func(*Config) {}},
+		{
+			name: "explicit zeros are kept",
+			cfg: &Config{
+				MaxBackups: Ptr(0),
+				MaxAge:     Ptr[Age](0),
+				BufferSize: Ptr[ByteSize](0),
+				Compress:   Ptr(false),
+				Console:    Ptr(false),
+			},
+			want: func(c *Config) {
+				c.MaxBackups, c.MaxAge, c.BufferSize = Ptr(0), Ptr[Age](0), Ptr[ByteSize](0)
+				c.Compress, c.Console = Ptr(false), Ptr(false)
+			},
+		},
+		{
+			name: "set values win",
+			cfg: &Config{
+				Level:         LogLevelDebug,
+				Filename:      "app.log",
+				MaxSize:       Ptr[FileSize](10 * MiB),
+				DisableCaller: true,
+			},
+			want: func(c *Config) {
+				c.Level, c.Filename, c.MaxSize, c.DisableCaller = LogLevelDebug, "app.log", Ptr[FileSize](10*MiB), true
+			},
+		},
+		{
+			name: "async settings are defaulted",
+			cfg:  &Config{EnableAsync: true},
+			want: func(c *Config) { c.EnableAsync = true },
+		},
+		{
+			name: "partial async settings",
+			cfg:  &Config{EnableAsync: true, AsyncFlushInterval: Ptr(Duration(50 * time.Millisecond))},
+			want: func(c *Config) { c.EnableAsync, c.AsyncFlushInterval = true, Ptr(Duration(50*time.Millisecond)) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before Config
+			if tt.cfg != nil {
+				before = *tt.cfg
+			}
+			want := defaultConfig()
+			tt.want(want)
+
+			got := mergeConfigWithDefault(tt.cfg)
+			assert.Equal(t, want, got)
+			if tt.cfg != nil {
+				// the caller's config is left alone
+				assert.Equal(t, before, *tt.cfg)
+				assert.NotSame(t, tt.cfg, got)
+				if tt.cfg.MaxBackups != nil {
+					assert.NotSame(t, tt.cfg.MaxBackups, got.MaxBackups)
+				}
+			}
+		})
+	}
+	// the defaults are not shared either
+	got := mergeConfigWithDefault(nil)
+	*got.MaxSize = 1
+	assert.Equal(t, def, defaultConfig())
+}
+
+func TestMergeConfigLayers(t *testing.T) {
+	base := &Config{Level: LogLevelWarn, MaxBackups: Ptr(3), Compress: Ptr(true)}
+	over := &Config{MaxBackups: Ptr(0), Compress: Ptr(false), Console: Ptr(false)}
+
+	got := mergeConfig(base, over)
+	assert.Equal(t, &Config{
+		Level:      LogLevelWarn,
+		MaxBackups: Ptr(0),
+		Compress:   Ptr(false),
+		Console:    Ptr(false),
+	}, got)
+	assert.Equal(t, Ptr(3), base.MaxBackups)
+	assert.Equal(t, &Config{}, mergeConfig(nil, nil))
+}
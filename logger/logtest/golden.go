@@ -0,0 +1,153 @@
+package logtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the current output")
+
+// VolatileKeys are the keys removed from log lines by AssertGolden when no
+// other keys are given, since their values change between runs.
+var VolatileKeys = []string{"time", "caller", "stacktrace"}
+
+// AssertGolden asserts that the JSON log lines in got match the golden file at
+// path once the volatile keys are removed from every line. A key may be a
+// dotted path such as "log.origin" to remove a nested member; a top-level key
+// containing dots is matched as is. When volatile is empty VolatileKeys is
+// used. Running the tests with -update rewrites the golden file instead.
+func AssertGolden(t testing.TB, path string, got []byte, volatile ...string) bool {
+	t.Helper()
+	if len(volatile) == 0 {
+		volatile = VolatileKeys
+	}
+
+	var lines []string
+	for _, line := range bytes.Split(bytes.TrimSpace(got), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		stripped, err := StripKeys(line, volatile...)
+		if !assert.NoError(t, err, "log line %s", line) {
+			return false
+		}
+		lines = append(lines, string(stripped))
+	}
+	actual := strings.Join(lines, "\n") + "\n"
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(actual), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return true
+	}
+
+	expected, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return assert.Fail(t, fmt.Sprintf("golden file %s does not exist, run the test with -update to create it", path))
+	}
+	if !assert.NoError(t, err) {
+		return false
+	}
+	return assert.Equal(t, string(expected), actual, "golden file %s, run the test with -update to accept the changes", path)
+}
+
+// AssertGolden compares the captured output with the golden file at path, see
+// the AssertGolden function.
+func (r *Recorder) AssertGolden(path string, volatile ...string) bool {
+	r.t.Helper()
+	return AssertGolden(r.t, path, r.Output(), volatile...)
+}
+
+// StripKeys removes keys from the JSON object line, keeping the order of the
+// remaining members. Keys are matched as described by AssertGolden.
+func StripKeys(line []byte, keys ...string) ([]byte, error) {
+	members, err := decodeObject(line)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		members, err = stripKey(members, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return encodeObject(members), nil
+}
+
+type member struct {
+	key   string
+	value json.RawMessage
+}
+
+func stripKey(members []member, key string) ([]member, error) {
+	out := members[:0]
+	for _, m := range members {
+		switch {
+		case m.key == key:
+			continue
+		case strings.HasPrefix(key, m.key+".") && bytes.HasPrefix(m.value, []byte("{")):
+			inner, err := decodeObject(m.value)
+			if err != nil {
+				return nil, err
+			}
+			if inner, err = stripKey(inner, strings.TrimPrefix(key, m.key+".")); err != nil {
+				return nil, err
+			}
+			m.value = encodeObject(inner)
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// decodeObject splits a JSON object into its members, in order.
+func decodeObject(data []byte) ([]member, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("not a JSON object: %s", data)
+	}
+	var members []member
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		members = append(members, member{key: tok.(string), value: value})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func encodeObject(members []member) []byte {
+	buf := bytes.NewBufferString("{")
+	for i, m := range members {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(m.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(m.value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
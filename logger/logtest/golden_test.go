@@ -0,0 +1,75 @@
+package logtest
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStripKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		keys []string
+		want string
+	}{
+		{
+			name: "top level keys keep the order",
+			line: `{"level":"info","time":"2025-06-08T18:35:28+08:00","caller":"demo/demo.go:28","msg":"hello","b":1,"a":2}`,
+			keys: VolatileKeys,
+			want: `{"level":"info","msg":"hello","b":1,"a":2}`,
+		},
+		{
+			name: "nested path",
+			line: `{"log":{"level":"info","origin":{"file.line":28}},"message":"hello"}`,
+			keys: []string{"log.origin"},
+			want: `{"log":{"level":"info"},"message":"hello"}`,
+		},
+		{
+			name: "dotted top level key",
+			line: `{"@timestamp":"2025-06-08T18:35:28Z","log.level":"info","log.origin":{"file.line":28}}`,
+			keys: []string{"@timestamp", "log.origin"},
+			want: `{"log.level":"info"}`,
+		},
+		{
+			name: "missing keys",
+			line: `{"msg":"hello","nested":{"a":[1,2]}}`,
+			keys: []string{"time", "nested.b", "msg.x"},
+			want: `{"msg":"hello","nested":{"a":[1,2]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := StripKeys([]byte(tt.line), tt.keys...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+
+	_, err := StripKeys([]byte(`["not","an","object"]`))
+	assert.Error(t, err)
+}
+
+func TestAssertGolden(t *testing.T) {
+	l, rec := New(t)
+	l.WithFields(zap.String("request_id", "req-1")).Info("request handled", zap.Int("status", 200))
+	l.Error("request failed", zap.Error(errors.New("timeout")))
+
+	rec.AssertGolden(filepath.Join("testdata", "recorder.golden"))
+}
+
+func TestAssertGoldenMismatch(t *testing.T) {
+	ft := &fakeT{}
+	ft.run(func() {
+		AssertGolden(ft, filepath.Join("testdata", "recorder.golden"), []byte(`{"level":"info","msg":"other"}`))
+		AssertGolden(ft, filepath.Join("testdata", "missing.golden"), []byte(`{"msg":"x"}`))
+	})
+	assert.True(t, ft.failed)
+	require.Len(t, ft.messages, 2)
+	assert.Contains(t, ft.messages[0], "run the test with -update to accept the changes")
+	assert.Contains(t, ft.messages[1], "does not exist")
+}
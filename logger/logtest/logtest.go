@@ -0,0 +1,159 @@
+// Package logtest captures what code logs through a *logger.Logger so tests can
+// assert on it instead of reading log files.
+package logtest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/double12gzh/zap-demo/logger"
+)
+
+// Option configures New.
+type Option func(*options)
+
+type options struct {
+	level  zapcore.LevelEnabler
+	config *logger.Config
+}
+
+// WithLevel sets the lowest level captured, debug by default.
+func WithLevel(level zapcore.LevelEnabler) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithConfig sets the config whose encoder, caller and stack trace settings
+// are used to produce Output, so that it matches what NewLogger would write to
+// the log file. The output settings of config are ignored.
+func WithConfig(config *logger.Config) Option {
+	return func(o *options) {
+		o.config = config
+	}
+}
+
+// Recorder holds the entries logged through the logger returned by New. The
+// embedded ObservedLogs provides All, Len, FilterField, FilterMessage and the
+// other filters of zaptest/observer.
+type Recorder struct {
+	*observer.ObservedLogs
+
+	t   testing.TB
+	out syncBuffer
+}
+
+// New returns a logger capturing every entry in memory and the Recorder to
+// inspect them. The entries are also encoded as JSON lines like NewLogger
+// encodes them, see Output. When t fails, the captured lines are written to
+// the test log.
+func New(t testing.TB, opts ...Option) (*logger.Logger, *Recorder) {
+	t.Helper()
+	o := options{level: zapcore.DebugLevel, config: &logger.Config{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	observed, logs := observer.New(o.level)
+	r := &Recorder{ObservedLogs: logs, t: t}
+	encoded := zapcore.NewCore(logger.NewEncoder(o.config), &r.out, o.level)
+
+	var zapOpts []zap.Option
+	if o.config.DisableCaller {
+		zapOpts = append(zapOpts, zap.WithCaller(false))
+	}
+	if !o.config.DisableStacktrace {
+		zapOpts = append(zapOpts, zap.AddStacktrace(zapcore.ErrorLevel))
+	}
+	l := logger.NewLoggerWithCore(zapcore.NewTee(observed, encoded), zapOpts...)
+
+	t.Cleanup(func() {
+		if t.Failed() && r.Len() > 0 {
+			t.Logf("captured logs:\n%s", r.Output())
+		}
+	})
+	return l, r
+}
+
+// Output returns the captured entries encoded as JSON lines.
+func (r *Recorder) Output() []byte {
+	return r.out.Bytes()
+}
+
+// AssertLogged asserts that an entry with the given level and message was
+// logged carrying at least the given fields, including the fields added to the
+// logger, and reports whether it was.
+func (r *Recorder) AssertLogged(level zapcore.Level, msg string, fields ...zap.Field) bool {
+	r.t.Helper()
+	want := fieldsMap(fields)
+	for _, e := range r.All() {
+		if e.Level == level && e.Message == msg && containsFields(e.ContextMap(), want) {
+			return true
+		}
+	}
+	return assert.Fail(r.t, fmt.Sprintf("no %s entry %q with fields %v", level, msg, want), "captured logs:\n%s", r.Output())
+}
+
+// RequireNoErrors stops the test when an entry at error level or above was
+// logged.
+func (r *Recorder) RequireNoErrors() {
+	r.t.Helper()
+	var errs []string
+	for _, e := range r.All() {
+		if e.Level >= zapcore.ErrorLevel {
+			errs = append(errs, fmt.Sprintf("%s %q %v", e.Level, e.Message, e.ContextMap()))
+		}
+	}
+	if len(errs) > 0 {
+		r.t.Fatalf("%d entries logged at error level or above:\n%s", len(errs), strings.Join(errs, "\n"))
+	}
+}
+
+// fieldsMap encodes fields the way observer.LoggedEntry.ContextMap does.
+func fieldsMap(fields []zap.Field) map[string]any {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return enc.Fields
+}
+
+func containsFields(got, want map[string]any) bool {
+	for k, v := range want {
+		g, ok := got[k]
+		if !ok || !assert.ObjectsAreEqual(v, g) {
+			return false
+		}
+	}
+	return true
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use as a zapcore.WriteSyncer.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Sync() error {
+	return nil
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
@@ -0,0 +1,128 @@
+package logtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/double12gzh/zap-demo/logger"
+)
+
+// fakeT records failures instead of failing the running test.
+type fakeT struct {
+	testing.TB
+	failed   bool
+	messages []string
+	cleanups []func()
+}
+
+// errFailNow stops a fakeT test function like FailNow stops a real one.
+var errFailNow = errors.New("FailNow")
+
+func (t *fakeT) Helper()          {}
+func (t *fakeT) Name() string     { return "fake" }
+func (t *fakeT) Failed() bool     { return t.failed }
+func (t *fakeT) Cleanup(f func()) { t.cleanups = append(t.cleanups, f) }
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.failed = true
+	t.Logf(format, args...)
+}
+func (t *fakeT) Fatalf(format string, args ...any) {
+	t.Errorf(format, args...)
+	panic(errFailNow)
+}
+func (t *fakeT) Logf(format string, args ...any) {
+	t.messages = append(t.messages, fmt.Sprintf(format, args...))
+}
+
+// run calls f like the testing package calls a test function.
+func (t *fakeT) run(f func()) {
+	defer func() {
+		if r := recover(); r != nil && r != errFailNow {
+			panic(r)
+		}
+		for i := len(t.cleanups) - 1; i >= 0; i-- {
+			t.cleanups[i]()
+		}
+	}()
+	f()
+}
+
+func TestRecorder(t *testing.T) {
+	l, rec := New(t)
+
+	ctx := logger.NewContextWithValue(context.Background(), l.WithFields(zap.String("user", "john")))
+	logger.InfoCtx(ctx, "user logged in", zap.Int("age", 30))
+	l.Debug("debug message")
+	l.Warn("disk almost full", zap.Float64("usage", 0.93))
+
+	rec.AssertLogged(zapcore.InfoLevel, "user logged in", zap.String("user", "john"), zap.Int("age", 30))
+	rec.AssertLogged(zapcore.DebugLevel, "debug message")
+	rec.AssertLogged(zapcore.WarnLevel, "disk almost full")
+	rec.RequireNoErrors()
+
+	assert.Equal(t, 1, rec.FilterField(zap.String("user", "john")).Len())
+	assert.Equal(t, 3, rec.Len())
+
+	// output is encoded like the log files
+	out := string(rec.Output())
+	assert.Contains(t, out, `"level":"info"`)
+	assert.Contains(t, out, `"caller":"logtest/logtest_test.go:`)
+	assert.Contains(t, out, `"msg":"user logged in","user":"john","age":30`)
+}
+
+func TestRecorderOptions(t *testing.T) {
+	l, rec := New(t, WithLevel(zapcore.InfoLevel), WithConfig(&logger.Config{DisableCaller: true}))
+	l.Debug("hidden")
+	l.Info("visible")
+
+	assert.Equal(t, 1, rec.Len())
+	assert.NotContains(t, string(rec.Output()), "caller")
+}
+
+func TestRecorderFailures(t *testing.T) {
+	ft := &fakeT{}
+	ft.run(func() {
+		l, rec := New(ft)
+		l.Info("user logged in", zap.String("user", "john"))
+
+		assert.True(t, rec.AssertLogged(zapcore.InfoLevel, "user logged in", zap.String("user", "john")))
+		assert.False(t, ft.failed)
+
+		assert.False(t, rec.AssertLogged(zapcore.InfoLevel, "user logged in", zap.String("user", "jane")))
+		assert.False(t, rec.AssertLogged(zapcore.WarnLevel, "user logged in"))
+		assert.True(t, ft.failed)
+
+		l.Error("payment failed", zap.Error(errors.New("card declined")))
+		rec.RequireNoErrors()
+		t.Error("RequireNoErrors did not stop the test")
+	})
+
+	joined := strings.Join(ft.messages, "\n")
+	assert.Contains(t, joined, `no info entry "user logged in" with fields map[user:jane]`)
+	assert.Contains(t, joined, `1 entries logged at error level or above:`)
+	assert.Contains(t, joined, `error "payment failed" map[error:card declined]`)
+
+	// the captured logs are dumped once the test failed
+	require.NotEmpty(t, ft.messages)
+	last := ft.messages[len(ft.messages)-1]
+	assert.True(t, strings.HasPrefix(last, "captured logs:\n"), last)
+	assert.Contains(t, last, `"msg":"payment failed"`)
+}
+
+func TestRecorderNoDumpOnSuccess(t *testing.T) {
+	ft := &fakeT{}
+	ft.run(func() {
+		l, _ := New(ft)
+		l.Info("fine")
+	})
+	assert.False(t, ft.failed)
+	assert.Empty(t, ft.messages)
+}
@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// Keys of the metadata fields.
+const (
+	hostKey         = "host"
+	pidKey          = "pid"
+	goVersionKey    = "go_version"
+	vcsRevisionKey  = "vcs_revision"
+	k8sPodKey       = "k8s_pod"
+	k8sNamespaceKey = "k8s_namespace"
+	k8sNodeKey      = "k8s_node"
+)
+
+// Kubernetes downward API variables read for the kubernetes metadata, set in
+// the pod spec with fieldRef metadata.name, metadata.namespace and
+// spec.nodeName.
+const (
+	PodNameEnvVar      = "POD_NAME"
+	PodNamespaceEnvVar = "POD_NAMESPACE"
+	NodeNameEnvVar     = "NODE_NAME"
+)
+
+// MetadataConfig selects the metadata detected when the logger is created and
+// added to every entry. Every value is off unless set to true, an overlay can
+// turn it off again with false.
+type MetadataConfig struct {
+	Hostname    *bool `json:"hostname" yaml:"hostname"`         // host: the host name
+	PID         *bool `json:"pid" yaml:"pid"`                   // pid: the process id
+	GoVersion   *bool `json:"go_version" yaml:"go_version"`     // go_version: the Go version the binary was built with
+	VCSRevision *bool `json:"vcs_revision" yaml:"vcs_revision"` // vcs_revision: the commit the binary was built from, with -dirty for local changes
+	Kubernetes  *bool `json:"kubernetes" yaml:"kubernetes"`     // k8s_pod, k8s_namespace and k8s_node: from the downward API variables that are set
+}
+
+// staticFields returns the fields added to every entry of a logger created
+// from c: the detected metadata, then the fields of c sorted by key. c must
+// have its defaults set. Metadata that cannot be detected and empty field
+// values are left out.
+func (c *Config) staticFields() []zap.Field {
+	var fields []zap.Field
+	m := c.Metadata
+	if *m.Hostname {
+		if host, err := os.Hostname(); err == nil {
+			fields = append(fields, zap.String(hostKey, host))
+		}
+	}
+	if *m.PID {
+		fields = append(fields, zap.Int(pidKey, os.Getpid()))
+	}
+	if *m.GoVersion {
+		fields = append(fields, zap.String(goVersionKey, runtime.Version()))
+	}
+	if *m.VCSRevision {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			if rev := vcsRevision(info); rev != "" {
+				fields = append(fields, zap.String(vcsRevisionKey, rev))
+			}
+		}
+	}
+	if *m.Kubernetes {
+		for _, v := range []struct{ key, env string }{
+			{k8sPodKey, PodNameEnvVar},
+			{k8sNamespaceKey, PodNamespaceEnvVar},
+			{k8sNodeKey, NodeNameEnvVar},
+		} {
+			if value := os.Getenv(v.env); value != "" {
+				fields = append(fields, zap.String(v.key, value))
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(c.Fields))
+	for k := range c.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if v := c.Fields[k]; v != "" {
+			fields = append(fields, zap.String(k, v))
+		}
+	}
+	// a field set in the config replaces the detected value of the same key
+	return dedupeFields(fields)
+}
+
+// vcsRevision returns the VCS revision recorded in info by go build, or ""
+// when there is none.
+func vcsRevision(info *debug.BuildInfo) string {
+	var rev, modified string
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			rev = s.Value
+		case "vcs.modified":
+			modified = s.Value
+		}
+	}
+	if rev != "" && modified == "true" {
+		rev += "-dirty"
+	}
+	return rev
+}
+
+// validateFields reports the keys of fields that cannot be used, entryKeys are
+// the keys of the entry itself.
+func validateFields(fields map[string]string, entryKeys map[string]bool) []*FieldError {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var errs []*FieldError
+	for _, k := range keys {
+		switch {
+		case k == "":
+			errs = append(errs, &FieldError{Path: "fields", Message: "empty key"})
+		case entryKeys[k]:
+			errs = append(errs, &FieldError{Path: "fields", Message: fmt.Sprintf("key %q is used by every entry", k)})
+		}
+	}
+	return errs
+}
+
+// pickMetadata sets the toggles of dst that are set in v, see mergeConfig.
+func pickMetadata(dst *MetadataConfig, v MetadataConfig) {
+	pickPtr(&dst.Hostname, v.Hostname)
+	pickPtr(&dst.PID, v.PID)
+	pickPtr(&dst.GoVersion, v.GoVersion)
+	pickPtr(&dst.VCSRevision, v.VCSRevision)
+	pickPtr(&dst.Kubernetes, v.Kubernetes)
+}
+
+// pickFields copies the fields of v into dst, replacing the values of the keys
+// they share.
+func pickFields(dst *map[string]string, v map[string]string) {
+	if len(v) == 0 {
+		return
+	}
+	if *dst == nil {
+		*dst = make(map[string]string, len(v))
+	}
+	for k, value := range v {
+		(*dst)[k] = value
+	}
+}
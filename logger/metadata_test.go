@@ -0,0 +1,171 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// readEntries decodes the JSON lines of the log file at path.
+func readEntries(t *testing.T, path string) []map[string]any {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var entries []map[string]any
+	for s := bufio.NewScanner(f); s.Scan(); {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(s.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestLoggerStaticFields(t *testing.T) {
+	t.Setenv(PodNameEnvVar, "api-7d9f")
+	t.Setenv(PodNamespaceEnvVar, "payments")
+	t.Setenv(NodeNameEnvVar, "")
+	dir := t.TempDir()
+	log, err := NewLogger(&Config{
+		Filename:      filepath.Join(dir, "app.log"),
+		ErrorFilename: filepath.Join(dir, "error.log"),
+		Console:       Ptr(false),
+		Fields:        map[string]string{"service": "payment", "version": "1.2.3", "pid": "main", "env": ""},
+		Metadata: MetadataConfig{
+			Hostname:   Ptr(true),
+			PID:        Ptr(true),
+			GoVersion:  Ptr(true),
+			Kubernetes: Ptr(true),
+		},
+	})
+	require.NoError(t, err)
+
+	log.Info("root")
+	log.WithFields(zap.String("req", "a")).WithLevel(zapcore.DebugLevel).Debug("derived")
+	log.WithContext(StoreFieldsInContext(context.Background(), zap.String("req", "b"))).Info("context")
+	require.NoError(t, log.Close(context.Background()))
+
+	host, err := os.Hostname()
+	require.NoError(t, err)
+	entries := readEntries(t, filepath.Join(dir, "app.log"))
+	require.Len(t, entries, 3)
+	for _, entry := range entries {
+		assert.Equal(t, host, entry["host"])
+		// the configured field wins over the detected one
+		assert.Equal(t, "main", entry["pid"])
+		assert.Equal(t, runtime.Version(), entry["go_version"])
+		assert.Equal(t, "api-7d9f", entry["k8s_pod"])
+		assert.Equal(t, "payments", entry["k8s_namespace"])
+		assert.NotContains(t, entry, "k8s_node")
+		assert.Equal(t, "payment", entry["service"])
+		assert.Equal(t, "1.2.3", entry["version"])
+		assert.NotContains(t, entry, "env")
+	}
+	assert.Equal(t, "a", entries[1]["req"])
+	assert.Equal(t, "b", entries[2]["req"])
+}
+
+func TestStaticFieldsOptIn(t *testing.T) {
+	t.Setenv(PodNameEnvVar, "api-7d9f")
+	assert.Empty(t, mergeConfigWithDefault(nil).staticFields())
+
+	fields := mergeConfigWithDefault(&Config{Metadata: MetadataConfig{PID: Ptr(true)}}).staticFields()
+	assert.Equal(t, []zap.Field{zap.Int("pid", os.Getpid())}, fields)
+}
+
+func TestVCSRevision(t *testing.T) {
+	info := &debug.BuildInfo{Settings: []debug.BuildSetting{
+		{Key: "vcs", Value: "git"},
+		{Key: "vcs.revision", Value: "0d6033d"},
+		{Key: "vcs.modified", Value: "false"},
+	}}
+	assert.Equal(t, "0d6033d", vcsRevision(info))
+
+	info.Settings[2].Value = "true"
+	assert.Equal(t, "0d6033d-dirty", vcsRevision(info))
+
+	assert.Equal(t, "", vcsRevision(&debug.BuildInfo{}))
+}
+
+func TestLoadConfigFromYamlFields(t *testing.T) {
+	t.Setenv(AppEnvVar, "prod")
+	t.Setenv("APP_VERSION", "1.2.3")
+	dir := writeConfigFiles(t, map[string]string{
+		"log.yaml": `
+logger:
+  filename: $DIR/app.log
+  fields:
+    service: payment
+    version: ${APP_VERSION:-dev}
+    env: dev
+  metadata:
+    hostname: true
+    pid: true
+`,
+		"log.prod.yaml": `
+logger:
+  fields:
+    env: prod
+  metadata:
+    pid: false
+`,
+	})
+
+	config, err := LoadConfigFromYaml(filepath.Join(dir, "log.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"service": "payment", "version": "1.2.3", "env": "prod"}, config.Fields)
+	assert.Equal(t, MetadataConfig{Hostname: Ptr(true), PID: Ptr(false)}, config.Metadata)
+
+	t.Setenv(AppEnvVar, "")
+	path := writeConfig(t, `
+logger:
+  filename: $DIR/app.log
+  fields:
+    msg: hello
+    service: [payment]
+  metadata:
+    hostnme: true
+`)
+	_, err = LoadConfigFromYaml(path)
+	var invalid *ValidationError
+	require.ErrorAs(t, err, &invalid)
+	var got []string
+	for _, fe := range invalid.Errors {
+		got = append(got, fe.Error())
+	}
+	assert.Equal(t, []string{
+		"logger.fields (line 5, column 5): cannot unmarshal !!seq into string",
+		`logger.metadata.hostnme (line 8, column 5): unknown key "hostnme", did you mean "hostname"?`,
+	}, got)
+
+	err = (&Config{Fields: map[string]string{"msg": "hello", "": "x"}}).Validate()
+	assert.EqualError(t, err, `invalid logger config, 2 problems:`+"\n"+
+		"\tfields: empty key\n"+
+		`	fields: key "msg" is used by every entry`)
+}
+
+func TestMergeConfigFields(t *testing.T) {
+	base := &Config{Fields: map[string]string{"service": "payment", "env": "dev"}}
+	over := &Config{Fields: map[string]string{"env": "prod"}, Metadata: MetadataConfig{PID: Ptr(true)}}
+
+	merged := mergeConfig(base, over)
+	assert.Equal(t, map[string]string{"service": "payment", "env": "prod"}, merged.Fields)
+	assert.Equal(t, MetadataConfig{PID: Ptr(true)}, merged.Metadata)
+
+	// the inputs are left alone
+	merged.Fields["service"] = "billing"
+	*merged.Metadata.PID = false
+	assert.Equal(t, map[string]string{"service": "payment", "env": "dev"}, base.Fields)
+	assert.Equal(t, Ptr(true), over.Metadata.PID)
+}
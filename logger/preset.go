@@ -0,0 +1,272 @@
+package logger
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// Presets of EncodingConfig, writing entries in the schema of a log backend.
+const (
+	// PresetECS follows the Elastic Common Schema: log.level, @timestamp,
+	// log.origin, error.stack_trace, trace.id and ecs.version.
+	PresetECS = "ecs"
+	// PresetGCP follows the structured logging of Google Cloud Logging:
+	// severity, the logging.googleapis.com/sourceLocation of the caller and
+	// logging.googleapis.com/trace from the trace id.
+	PresetGCP = "gcp"
+	// PresetDatadog follows the standard attributes of Datadog: level, which
+	// Datadog reads as the status without clashing with HTTP status fields,
+	// timestamp in milliseconds, error.stack, and dd.trace_id and dd.span_id
+	// converted to the 64-bit decimal ids of Datadog.
+	PresetDatadog = "datadog"
+)
+
+// Keys of the W3C trace context fields, which the presets map to their
+// schema.
+const (
+	TraceIDKey    = "trace_id"
+	SpanIDKey     = "span_id"
+	TraceFlagsKey = "trace_flags"
+)
+
+// GCPProjectEnvVar holds the Google Cloud project of the gcp preset. When it is
+// set, trace ids are written as projects/PROJECT/traces/TRACE_ID, the form
+// Cloud Logging links to Cloud Trace.
+const GCPProjectEnvVar = "GOOGLE_CLOUD_PROJECT"
+
+// ecsVersion is the version of ECS written by the ecs preset.
+const ecsVersion = "8.11.0"
+
+const errorKey = "error"
+
+// presetEncodings holds the settings of each preset, which apply under the
+// ones set explicitly in the config.
+var presetEncodings = map[string]EncodingConfig{
+	PresetECS: {
+		MessageKey:    Ptr("message"),
+		LevelKey:      Ptr("log.level"),
+		TimeKey:       Ptr("@timestamp"),
+		NameKey:       Ptr("log.logger"),
+		CallerKey:     Ptr("log.origin"),
+		StacktraceKey: Ptr("error.stack_trace"),
+		Level:         "lowercase",
+		Time:          "rfc3339nano",
+		Duration:      "nanos",
+	},
+	PresetGCP: {
+		MessageKey:    Ptr("message"),
+		LevelKey:      Ptr("severity"),
+		TimeKey:       Ptr("time"),
+		NameKey:       Ptr("logger"),
+		CallerKey:     Ptr("logging.googleapis.com/sourceLocation"),
+		StacktraceKey: Ptr("stack_trace"),
+		Level:         "gcp",
+		Time:          "rfc3339nano",
+	},
+	PresetDatadog: {
+		MessageKey:    Ptr("message"),
+		LevelKey:      Ptr("level"),
+		TimeKey:       Ptr("timestamp"),
+		NameKey:       Ptr("logger.name"),
+		StacktraceKey: Ptr("error.stack"),
+		Level:         "lowercase",
+		Time:          "epoch_millis",
+		Duration:      "nanos",
+	},
+}
+
+// callerObjectPreset reports whether preset writes the caller as an object,
+// instead of the string of the caller encoder.
+func callerObjectPreset(preset string) bool {
+	return preset == PresetECS || preset == PresetGCP
+}
+
+// gcpSeverityEncoder writes the levels as the LogSeverity of Cloud Logging.
+func gcpSeverityEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	switch l {
+	case zapcore.DebugLevel:
+		enc.AppendString("DEBUG")
+	case zapcore.InfoLevel:
+		enc.AppendString("INFO")
+	case zapcore.WarnLevel:
+		enc.AppendString("WARNING")
+	case zapcore.ErrorLevel:
+		enc.AppendString("ERROR")
+	case zapcore.DPanicLevel:
+		enc.AppendString("CRITICAL")
+	case zapcore.PanicLevel:
+		enc.AppendString("ALERT")
+	case zapcore.FatalLevel:
+		enc.AppendString("EMERGENCY")
+	default:
+		enc.AppendString("DEFAULT")
+	}
+}
+
+// presetEncoder writes entries in the schema of a preset on top of the
+// encoder configured with the keys of the preset.
+type presetEncoder struct {
+	zapcore.Encoder
+
+	// caller writes the caller of an entry under callerKey, nil when the
+	// wrapped encoder writes it
+	caller    func(c zapcore.EntryCaller) zapcore.ObjectMarshaler
+	callerKey string
+	// rewrite writes the string fields of the keys in the schema of the preset
+	rewrite map[string]func(enc zapcore.ObjectEncoder, v string)
+	// ecs is set for the ecs preset, which adds ecs.version to every entry
+	ecs bool
+}
+
+// newPresetEncoder wraps enc, built from the encoder config of c, to write the
+// preset of c.
+func newPresetEncoder(c *Config, enc zapcore.Encoder) *presetEncoder {
+	e := &presetEncoder{Encoder: enc}
+	full := c.Encoder.Caller == "full"
+	if !c.DisableCaller {
+		e.callerKey = *c.Encoder.CallerKey
+	}
+
+	switch c.Encoder.Preset {
+	case PresetECS:
+		e.ecs = true
+		e.caller = func(c zapcore.EntryCaller) zapcore.ObjectMarshaler {
+			return ecsOrigin{file: callerFile(c, full), line: c.Line, function: c.Function}
+		}
+		e.rewrite = map[string]func(zapcore.ObjectEncoder, string){
+			TraceIDKey: renameString("trace.id"),
+			SpanIDKey:  renameString("span.id"),
+			errorKey:   renameString("error.message"),
+		}
+	case PresetGCP:
+		project := os.Getenv(GCPProjectEnvVar)
+		e.caller = func(c zapcore.EntryCaller) zapcore.ObjectMarshaler {
+			return gcpSourceLocation{file: callerFile(c, full), line: c.Line, function: c.Function}
+		}
+		e.rewrite = map[string]func(zapcore.ObjectEncoder, string){
+			TraceIDKey: func(enc zapcore.ObjectEncoder, v string) {
+				if project != "" {
+					v = "projects/" + project + "/traces/" + v
+				}
+				enc.AddString("logging.googleapis.com/trace", v)
+			},
+			SpanIDKey: renameString("logging.googleapis.com/spanId"),
+			TraceFlagsKey: func(enc zapcore.ObjectEncoder, v string) {
+				flags, err := strconv.ParseUint(v, 16, 8)
+				enc.AddBool("logging.googleapis.com/trace_sampled", err == nil && flags&1 == 1)
+			},
+		}
+	case PresetDatadog:
+		e.rewrite = map[string]func(zapcore.ObjectEncoder, string){
+			TraceIDKey: datadogID("dd.trace_id"),
+			SpanIDKey:  datadogID("dd.span_id"),
+			errorKey:   renameString("error.message"),
+		}
+	}
+	return e
+}
+
+func (e *presetEncoder) Clone() zapcore.Encoder {
+	clone := *e
+	clone.Encoder = e.Encoder.Clone()
+	return &clone
+}
+
+func (e *presetEncoder) AddString(key, v string) {
+	if rewrite, ok := e.rewrite[key]; ok {
+		rewrite(e.Encoder, v)
+		return
+	}
+	e.Encoder.AddString(key, v)
+}
+
+func (e *presetEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	// the values of the preset and the fields are written as context of the
+	// entry, the fields going through AddString
+	clone := e.Clone().(*presetEncoder)
+	if e.ecs {
+		clone.Encoder.AddString("ecs.version", ecsVersion)
+	}
+	if e.caller != nil && e.callerKey != "" && ent.Caller.Defined {
+		_ = clone.Encoder.AddObject(e.callerKey, e.caller(ent.Caller))
+	}
+	for _, f := range fields {
+		f.AddTo(clone)
+	}
+	return clone.Encoder.EncodeEntry(ent, nil)
+}
+
+func renameString(key string) func(zapcore.ObjectEncoder, string) {
+	return func(enc zapcore.ObjectEncoder, v string) {
+		enc.AddString(key, v)
+	}
+}
+
+// datadogID writes the W3C trace or span id v under key as the decimal 64-bit
+// id used by Datadog, from the last 16 hex digits. Invalid ids are written as
+// they are.
+func datadogID(key string) func(zapcore.ObjectEncoder, string) {
+	return func(enc zapcore.ObjectEncoder, v string) {
+		hex := v
+		if len(hex) > 16 {
+			hex = hex[len(hex)-16:]
+		}
+		if id, err := strconv.ParseUint(hex, 16, 64); err == nil {
+			v = strconv.FormatUint(id, 10)
+		}
+		enc.AddString(key, v)
+	}
+}
+
+// callerFile returns the file of c, as its directory and name like the short
+// caller encoder unless full.
+func callerFile(c zapcore.EntryCaller, full bool) string {
+	if full {
+		return c.File
+	}
+	i := strings.LastIndexByte(c.File, '/')
+	if i < 0 {
+		return c.File
+	}
+	if i = strings.LastIndexByte(c.File[:i], '/'); i < 0 {
+		return c.File
+	}
+	return c.File[i+1:]
+}
+
+// ecsOrigin is the log.origin of ECS.
+type ecsOrigin struct {
+	file     string
+	line     int
+	function string
+}
+
+func (o ecsOrigin) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("file.name", o.file)
+	enc.AddInt("file.line", o.line)
+	if o.function != "" {
+		enc.AddString("function", o.function)
+	}
+	return nil
+}
+
+// gcpSourceLocation is the LogEntrySourceLocation of Cloud Logging, whose line
+// is an int64 written as a string.
+type gcpSourceLocation struct {
+	file     string
+	line     int
+	function string
+}
+
+func (l gcpSourceLocation) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("file", l.file)
+	enc.AddString("line", strconv.Itoa(l.line))
+	if l.function != "" {
+		enc.AddString("function", l.function)
+	}
+	return nil
+}
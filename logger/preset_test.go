@@ -0,0 +1,134 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/double12gzh/zap-demo/logger"
+	"github.com/double12gzh/zap-demo/logger/logtest"
+)
+
+// the golden tests live outside package logger, since logtest imports it
+
+func TestPresetGolden(t *testing.T) {
+	t.Setenv(logger.GCPProjectEnvVar, "demo-project")
+	stackKeys := map[string]string{
+		logger.PresetECS:     "error.stack_trace",
+		logger.PresetGCP:     "stack_trace",
+		logger.PresetDatadog: "error.stack",
+	}
+	volatile := map[string][]string{
+		logger.PresetECS:     {"@timestamp", "log.origin.file.line", stackKeys[logger.PresetECS]},
+		logger.PresetGCP:     {"time", "logging.googleapis.com/sourceLocation.line", stackKeys[logger.PresetGCP]},
+		logger.PresetDatadog: {"timestamp", "caller", stackKeys[logger.PresetDatadog]},
+	}
+	for _, preset := range []string{logger.PresetECS, logger.PresetGCP, logger.PresetDatadog} {
+		t.Run(preset, func(t *testing.T) {
+			l, rec := logtest.New(t, logtest.WithConfig(&logger.Config{
+				Encoder: logger.EncodingConfig{Preset: preset},
+			}))
+
+			traced := l.WithFields(
+				zap.String(logger.TraceIDKey, "4bf92f3577b34da6a3ce929d0e0e4736"),
+				zap.String(logger.SpanIDKey, "00f067aa0ba902b7"),
+				zap.String(logger.TraceFlagsKey, "01"),
+			)
+			traced.Info("request handled", zap.Int("status", 200), zap.Duration("took", 1500*time.Millisecond))
+			traced.Warn("slow request")
+			l.Error("request failed", zap.Error(errors.New("timeout")))
+
+			// the stack trace is left out of the golden file, but must be
+			// written under the key of the preset
+			lines := bytes.Split(bytes.TrimSpace(rec.Output()), []byte("\n"))
+			var entry map[string]any
+			require.NoError(t, json.Unmarshal(lines[len(lines)-1], &entry))
+			assert.Contains(t, entry[stackKeys[preset]], "TestPresetGolden")
+
+			rec.AssertGolden(filepath.Join("testdata", "preset_"+preset+".golden"), volatile[preset]...)
+		})
+	}
+}
+
+func TestPresetOverrides(t *testing.T) {
+	// the values set next to the preset win
+	l, rec := logtest.New(t, logtest.WithConfig(&logger.Config{
+		DisableCaller: true,
+		Encoder: logger.EncodingConfig{
+			Preset:     logger.PresetGCP,
+			MessageKey: logger.Ptr("msg"),
+			Time:       "epoch",
+		},
+	}))
+	l.Debug("hello")
+	rec.AssertGolden(filepath.Join("testdata", "preset_gcp_overrides.golden"), "time")
+	assert.Contains(t, string(rec.Output()), `"time":1`)
+}
+
+func TestNewLoggerPreset(t *testing.T) {
+	dir := t.TempDir()
+	l, err := logger.NewLogger(&logger.Config{
+		Filename:      filepath.Join(dir, "app.log"),
+		ErrorFilename: filepath.Join(dir, "error.log"),
+		Console:       logger.Ptr(false),
+		Encoder:       logger.EncodingConfig{Preset: logger.PresetECS},
+	})
+	require.NoError(t, err)
+	l.Info("started")
+	require.NoError(t, l.Close(context.Background()))
+
+	out, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	require.NoError(t, err)
+	assert.Regexp(t, `^\{"log.level":"info","@timestamp":"[^"]+","message":"started","ecs.version":"8.11.0","log.origin":\{"file.name":"logger/preset_test.go","file.line":\d+,"function":"[^"]+TestNewLoggerPreset"\}\}\n$`, string(out))
+}
+
+func TestPresetValidation(t *testing.T) {
+	err := (&logger.Config{Encoder: logger.EncodingConfig{Preset: "splunk"}}).Validate()
+	assert.EqualError(t, err, `invalid logger config: encoder.preset: unknown preset "splunk", expected one of datadog, ecs, gcp`)
+
+	// the preset decides the keys of the entry
+	err = (&logger.Config{
+		Fields:  map[string]string{"msg": "kept", "message": "hello"},
+		Encoder: logger.EncodingConfig{Preset: logger.PresetECS},
+	}).Validate()
+	assert.EqualError(t, err, `invalid logger config: fields: key "message" is used by every entry`)
+}
+
+func TestRepoConfigPreset(t *testing.T) {
+	// uncommenting the preset of the shipped config gives the whole schema of
+	// the preset, not a mix with the default keys
+	data, err := os.ReadFile(filepath.Join("..", "config", "log.yaml"))
+	require.NoError(t, err)
+	uncommented := strings.Replace(string(data), "# preset: ecs", "preset: ecs", 1)
+	require.NotEqual(t, string(data), uncommented)
+
+	dir := t.TempDir()
+	t.Setenv("LOG_DIR", dir)
+	t.Setenv(logger.AppEnvVar, "")
+	path := filepath.Join(dir, "log.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(uncommented), 0o644))
+
+	config, err := logger.LoadConfigFromYaml(path)
+	require.NoError(t, err)
+	config.Console = logger.Ptr(false)
+	config.Metadata = logger.MetadataConfig{}
+	config.Fields = nil
+	l, err := logger.NewLogger(config)
+	require.NoError(t, err)
+	l.Info("started")
+	require.NoError(t, l.Close(context.Background()))
+
+	out, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	require.NoError(t, err)
+	assert.Regexp(t, `^\{"log.level":"info","@timestamp":"[^"]+","message":"started","ecs.version":"8.11.0","log.origin":\{"file.name":"logger/preset_test.go","file.line":\d+,"function":"[^"]+TestRepoConfigPreset"\}\}\n$`, string(out))
+}
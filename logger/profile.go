@@ -0,0 +1,274 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AppEnvVar names the environment variable selecting the profile of a YAML
+// config. It takes precedence over the profile key of the file. Unlike the
+// profile key, it may name a profile without an overlay file, which then
+// leaves the config as it is, so every environment can set it.
+const AppEnvVar = "APP_ENV"
+
+// configLoader reads a YAML config file together with its includes and profile
+// overlay into a single document, remembering the file of every node.
+//
+// The document is built in this order, each step laid on top of the previous
+// one with merge:
+//
+//  1. the files listed by the include key of the config file, in order, each
+//     one built the same way from its own includes first;
+//  2. the config file itself;
+//  3. the profile overlay, built like the config file. The profile is named
+//     by AppEnvVar or else by the top-level profile key of the merged
+//     document, and its overlay is the file next to the config file with the
+//     profile before the extension: log.prod.yaml for log.yaml. An overlay
+//     missing for the profile of AppEnvVar is skipped, one missing for the
+//     profile key is an error.
+//
+// Placeholders are expanded in each file before it is merged. The EnvPrefix
+// overrides are applied to the result, so they win over every file.
+type configLoader struct {
+	files nodeFiles
+	vars  map[*yaml.Node][]string
+	errs  []*FieldError
+	stack []string // absolute paths of the files being loaded
+}
+
+func newConfigLoader() *configLoader {
+	return &configLoader{
+		files: nodeFiles{},
+		vars:  map[*yaml.Node][]string{},
+	}
+}
+
+// load builds the document of the config file at path with its profile.
+func (ld *configLoader) load(path string, format Format) (*yaml.Node, error) {
+	doc, err := ld.loadFile(path, format)
+	if err != nil {
+		return nil, err
+	}
+
+	key, value := popKey(doc, "profile")
+	profile, from := os.Getenv(AppEnvVar), &FieldError{Path: "profile", Message: AppEnvVar + ": "}
+	fromEnv := profile != ""
+	if profile == "" && value != nil {
+		if value.Kind != yaml.ScalarNode {
+			ld.errs = append(ld.errs, ld.files.errorAt(key, "profile", "profile must be a name"))
+			return doc, nil
+		}
+		profile, from = value.Value, ld.files.errorAt(value, "profile", "")
+	}
+	if profile == "" {
+		return doc, nil
+	}
+	if !validProfileName(profile) {
+		from.Message += fmt.Sprintf("invalid profile name %q, use letters, digits, - and _", profile)
+		ld.errs = append(ld.errs, from)
+		return doc, nil
+	}
+
+	overlay, err := ld.loadFile(profilePath(path, profile), FormatAuto)
+	if fromEnv && errors.Is(err, fs.ErrNotExist) {
+		return doc, nil
+	}
+	if err != nil {
+		from.Message += fmt.Sprintf("profile %q: %s", profile, err)
+		ld.errs = append(ld.errs, from)
+		return doc, nil
+	}
+	if key, _ := popKey(overlay, "profile"); key != nil {
+		ld.errs = append(ld.errs, ld.files.errorAt(key, "profile", "profile can only be set in the base config"))
+	}
+	return ld.merge(doc, overlay), nil
+}
+
+// loadFile reads the config file at path and loads it with loadData.
+func (ld *configLoader) loadFile(path string, format Format) (*yaml.Node, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range ld.stack {
+		if f == abs {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(ld.stack[i:], abs), " -> "))
+		}
+	}
+	ld.stack = append(ld.stack, abs)
+	defer func() { ld.stack = ld.stack[:len(ld.stack)-1] }()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return ld.loadData(path, data, format)
+}
+
+// loadData parses, expands and merges the config file name holding data with
+// its includes, which are relative to the directory of name. The format of
+// each file is detected unless given.
+func (ld *configLoader) loadData(name string, data []byte, format Format) (*yaml.Node, error) {
+	if format == FormatAuto {
+		format = formatOf(name, data)
+	}
+	doc, err := parseDocument(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to parse config file: %w", name, err)
+	}
+	path := name
+	ld.files.add(doc, path)
+
+	for _, fe := range expandEnv(doc, "", ld.vars) {
+		fe.File = path
+		ld.errs = append(ld.errs, fe)
+	}
+
+	key, value := popKey(doc, "include")
+	if key == nil {
+		return doc, nil
+	}
+	var includes []*yaml.Node
+	switch value.Kind {
+	case yaml.ScalarNode:
+		includes = []*yaml.Node{value}
+	case yaml.SequenceNode:
+		includes = value.Content
+	default:
+		ld.errs = append(ld.errs, ld.files.errorAt(key, "include", "include must be a file name or a list of file names"))
+	}
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, n := range includes {
+		if n.Kind != yaml.ScalarNode || n.Value == "" {
+			ld.errs = append(ld.errs, ld.files.errorAt(n, "include", "include must be a file name or a list of file names"))
+			continue
+		}
+		name := n.Value
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(filepath.Dir(path), name)
+		}
+		included, err := ld.loadFile(name, FormatAuto)
+		if err != nil {
+			ld.errs = append(ld.errs, ld.files.errorAt(n, "include", err.Error()))
+			continue
+		}
+		merged = ld.merge(merged, included)
+	}
+	return ld.merge(merged, doc), nil
+}
+
+// merge returns over laid on base, without modifying either:
+//
+//   - mappings are merged key by key, keys of base keep their order and new
+//     keys of over come after them;
+//   - any other value of over, including 0, false, "" and an empty list,
+//     replaces the value of base, so explicit zero values are kept;
+//   - a null value of over, written ~, null or left empty, removes the key,
+//     putting it back to its default. For a section, like logger, that resets
+//     every value below it.
+func (ld *configLoader) merge(base, over *yaml.Node) *yaml.Node {
+	base, over = resolveAlias(base), resolveAlias(over)
+	if base.Kind != yaml.MappingNode || over.Kind != yaml.MappingNode {
+		return over
+	}
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: over.Line, Column: over.Column}
+	if f, ok := ld.files[over]; ok {
+		ld.files[merged] = f
+	}
+	merged.Content = append(merged.Content, base.Content...)
+	for i := 0; i+1 < len(over.Content); i += 2 {
+		key, value := over.Content[i], resolveAlias(over.Content[i+1])
+		j := mappingIndex(merged, key.Value)
+		switch {
+		case isNull(value) && j >= 0:
+			merged.Content = append(merged.Content[:j:j], merged.Content[j+2:]...)
+		case isNull(value):
+		case j >= 0:
+			merged.Content[j], merged.Content[j+1] = key, ld.merge(merged.Content[j+1], value)
+		default:
+			merged.Content = append(merged.Content, key, value)
+		}
+	}
+	return merged
+}
+
+// nodeFiles maps the nodes of a merged document to the file they come from.
+type nodeFiles map[*yaml.Node]string
+
+// add records file for n and every node below it.
+func (f nodeFiles) add(n *yaml.Node, file string) {
+	f[n] = file
+	for _, c := range n.Content {
+		f.add(c, file)
+	}
+}
+
+// position sets the position of fe to the one of n.
+func (f nodeFiles) position(fe *FieldError, n *yaml.Node) {
+	fe.File, fe.Line, fe.Column = f[n], n.Line, n.Column
+}
+
+// errorAt returns a FieldError at the position of n.
+func (f nodeFiles) errorAt(n *yaml.Node, path, msg string) *FieldError {
+	fe := &FieldError{Path: path, Message: msg}
+	f.position(fe, n)
+	return fe
+}
+
+// popKey removes key from the mapping n and returns its key and value nodes,
+// or nils when n has no such key.
+func popKey(n *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	i := mappingIndex(n, key)
+	if i < 0 {
+		return nil, nil
+	}
+	k, v := n.Content[i], n.Content[i+1]
+	n.Content = append(n.Content[:i:i], n.Content[i+2:]...)
+	return k, v
+}
+
+// mappingIndex returns the index of key in the content of the mapping n, or
+// -1 when n is not a mapping or has no such key.
+func mappingIndex(n *yaml.Node, key string) int {
+	if n.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+func isNull(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null"
+}
+
+// profilePath returns the overlay of profile for the config file at path.
+func profilePath(path, profile string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + profile + ext
+}
+
+func validProfileName(name string) bool {
+	for _, c := range name {
+		if c != '-' && c != '_' && (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return name != ""
+}
@@ -0,0 +1,200 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// writeConfigFiles writes files to a temp dir, replacing $DIR in their content
+// with that dir, and returns the dir.
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(strings.ReplaceAll(content, "$DIR", dir)), 0o644))
+	}
+	return dir
+}
+
+func TestLoadConfigFromYamlProfile(t *testing.T) {
+	t.Setenv(AppEnvVar, "prod")
+	dir := writeConfigFiles(t, map[string]string{
+		"common.yaml": `
+logger:
+  time_format: 2006-01-02
+  max_backups: 7
+  max_age: 30
+`,
+		"log.yaml": `
+include: common.yaml
+profile: dev
+logger:
+  level: debug
+  filename: $DIR/app.log
+  console: true
+  compress: true
+`,
+		"log.prod.yaml": `
+logger:
+  level: warn
+  console: false
+  max_backups: 0
+  max_age: ~
+`,
+		"log.dev.yaml": `
+logger:
+  level: debug
+`,
+	})
+	path := filepath.Join(dir, "log.yaml")
+
+	config, sources, err := LoadConfigFromYamlWithSources(path)
+	require.NoError(t, err)
+	assert.Equal(t, LogLevelWarn, config.Level)
+	assert.Equal(t, filepath.Join(dir, "app.log"), config.Filename)
+	assert.Equal(t, "2006-01-02", config.TimeFormat)
+	// explicit zero values of the overlay win, null resets to the default
+	assert.Equal(t, Ptr(false), config.Console)
+	assert.Equal(t, Ptr(0), config.MaxBackups)
+	assert.Nil(t, config.MaxAge)
+	assert.Equal(t, Ptr(true), config.Compress)
+
+	prod := filepath.Join(dir, "log.prod.yaml")
+	assert.Equal(t, prod+":3", sources["level"].String())
+	assert.Equal(t, prod+":5", sources["max_backups"].String())
+	assert.Equal(t, filepath.Join(dir, "common.yaml")+":3", sources["time_format"].String())
+	assert.Equal(t, path+":8", sources["compress"].String())
+	assert.Equal(t, "default", sources["max_age"].String())
+
+	// without APP_ENV the profile key selects the overlay
+	t.Setenv(AppEnvVar, "")
+	config, err = LoadConfigFromYaml(path)
+	require.NoError(t, err)
+	assert.Equal(t, LogLevelDebug, config.Level)
+	assert.Equal(t, Ptr(true), config.Console)
+	assert.Equal(t, Ptr(7), config.MaxBackups)
+}
+
+func TestLoadConfigFromYamlProfileErrors(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"log.yaml": `
+include:
+  - common.yaml
+  - missing.yaml
+profile: staging
+logger:
+  level: info
+`,
+		"common.yaml": `
+include: log.yaml
+logger:
+  max_size: big
+`,
+		"log.staging.yaml": `
+profile: prod
+logger:
+  colour: red
+`,
+	})
+	path := filepath.Join(dir, "log.yaml")
+	common := filepath.Join(dir, "common.yaml")
+	staging := filepath.Join(dir, "log.staging.yaml")
+
+	_, err := LoadConfigFromYaml(path)
+	var invalid *ValidationError
+	require.ErrorAs(t, err, &invalid)
+
+	var got []string
+	for _, fe := range invalid.Errors {
+		got = append(got, fe.Error())
+	}
+	assert.Equal(t, []string{
+		"include (line 4, column 5): failed to read config file: open " + filepath.Join(dir, "missing.yaml") + ": no such file or directory",
+		"include (" + common + " line 2, column 10): include cycle: " + path + " -> " + common + " -> " + path,
+		"logger.max_size (" + common + ` line 4, column 13): invalid size "big", expected a number with an optional unit such as 256KiB`,
+		"profile (" + staging + " line 2, column 1): profile can only be set in the base config",
+		`logger.colour (` + staging + ` line 4, column 3): unknown key "colour"`,
+	}, got)
+
+	t.Setenv(AppEnvVar, "../prod")
+	_, err = LoadConfigFromYaml(path)
+	assert.ErrorContains(t, err, `profile: APP_ENV: invalid profile name "../prod", use letters, digits, - and _`)
+}
+
+func TestLoadConfigFromYamlMissingProfile(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"log.yaml": `
+logger:
+  level: info
+  filename: $DIR/app.log
+`,
+		"explicit.yaml": `
+profile: qa
+logger:
+  filename: $DIR/app.log
+`,
+	})
+
+	// APP_ENV may name a profile without an overlay
+	t.Setenv(AppEnvVar, "dev")
+	config, err := LoadConfigFromYaml(filepath.Join(dir, "log.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, LogLevelInfo, config.Level)
+
+	// the profile key must name an existing overlay
+	t.Setenv(AppEnvVar, "")
+	_, err = LoadConfigFromYaml(filepath.Join(dir, "explicit.yaml"))
+	assert.ErrorContains(t, err, `profile (line 2, column 10): profile "qa": failed to read config file: open `+filepath.Join(dir, "explicit.qa.yaml"))
+
+	// an overlay that cannot be read for another reason is still an error
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "log.staging.yaml"), 0o755))
+	t.Setenv(AppEnvVar, "staging")
+	_, err = LoadConfigFromYaml(filepath.Join(dir, "log.yaml"))
+	assert.ErrorContains(t, err, `profile: APP_ENV: profile "staging": failed to read config file`)
+}
+
+func TestConfigLoaderMerge(t *testing.T) {
+	parse := func(s string) *yaml.Node {
+		var n yaml.Node
+		require.NoError(t, yaml.Unmarshal([]byte(s), &n))
+		return n.Content[0]
+	}
+	base := parse("logger:\n  level: info\n  max_size: 100\n  console: true\nother: [a, b]\n")
+	over := parse("logger:\n  max_size: 0\n  console: ~\n  compress: false\nother: []\n")
+
+	ld := newConfigLoader()
+	out, err := yaml.Marshal(ld.merge(base, over))
+	require.NoError(t, err)
+	assert.Equal(t, "logger:\n    level: info\n    max_size: 0\n    compress: false\nother: []\n", string(out))
+
+	// the inputs are left alone
+	out, err = yaml.Marshal(base)
+	require.NoError(t, err)
+	assert.Equal(t, "logger:\n    level: info\n    max_size: 100\n    console: true\nother: [a, b]\n", string(out))
+}
+
+func TestLoadConfigFromYamlRepoProfile(t *testing.T) {
+	t.Setenv(AppEnvVar, "prod")
+	t.Setenv("LOG_DIR", t.TempDir())
+
+	config, err := LoadConfigFromYaml(filepath.Join("..", "config", "log.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, LogLevelInfo, config.Level)
+	assert.Equal(t, Ptr(false), config.Console)
+	assert.Equal(t, Ptr(10), config.MaxBackups)
+	assert.Equal(t, Ptr(Age(30*Day)), config.MaxAge)
+
+	// the environments without an overlay use the base config
+	for _, env := range []string{"dev", "staging"} {
+		t.Setenv(AppEnvVar, env)
+		config, err = LoadConfigFromYaml(filepath.Join("..", "config", "log.yaml"))
+		require.NoError(t, err, env)
+		assert.Equal(t, env, config.Fields["env"], env)
+	}
+}
@@ -0,0 +1,263 @@
+package logger
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// slogHandler is a slog.Handler writing to a zapcore.Core.
+//
+// Attributes added before the first WithGroup are encoded into the core once.
+// Attributes added inside groups are kept on the handler and nested at Handle
+// time, so that empty groups can be dropped as slog requires and context fields
+// stay at the top level.
+type slogHandler struct {
+	core      zapcore.Core
+	groups    []slogGroup
+	addCaller bool
+	addStack  zapcore.LevelEnabler
+}
+
+type slogGroup struct {
+	name  string
+	attrs []slog.Attr
+}
+
+// SlogHandlerOptions configures NewSlogHandlerFromCore.
+type SlogHandlerOptions struct {
+	// AddCaller records the caller taken from the slog record's PC.
+	AddCaller bool
+	// AddStacktrace records a stack trace for levels it enables; nil disables it.
+	AddStacktrace zapcore.LevelEnabler
+}
+
+// NewSlogHandler returns a slog.Handler that writes through l, sharing its
+// outputs, level and fields. Context fields stored with StoreFieldsInContext are
+// added to every record handled with that context.
+func NewSlogHandler(l *Logger) slog.Handler {
+	opts := SlogHandlerOptions{
+		AddCaller:     true,
+		AddStacktrace: zapcore.ErrorLevel,
+	}
+	if l.config != nil {
+		opts.AddCaller = !l.config.DisableCaller
+		if l.config.DisableStacktrace {
+			opts.AddStacktrace = nil
+		}
+	}
+	return NewSlogHandlerFromCore(l.logger.Core(), opts)
+}
+
+// NewSlogHandlerFromCore returns a slog.Handler writing to any zapcore.Core,
+// so that libraries taking a *slog.Logger can log into zap outputs.
+func NewSlogHandlerFromCore(core zapcore.Core, opts SlogHandlerOptions) slog.Handler {
+	return &slogHandler{
+		core:      core,
+		addCaller: opts.AddCaller,
+		addStack:  opts.AddStacktrace,
+	}
+}
+
+// InstallSlogDefault makes a slog.Logger backed by l the slog default logger.
+// As with slog.SetDefault, the standard log package is redirected as well.
+// The returned function restores the previous slog and log configuration.
+func InstallSlogDefault(l *Logger) (restore func()) {
+	prev := slog.Default()
+	prevWriter, prevFlags := log.Writer(), log.Flags()
+
+	slog.SetDefault(slog.New(NewSlogHandler(l)))
+
+	return func() {
+		slog.SetDefault(prev)
+		log.SetOutput(prevWriter)
+		log.SetFlags(prevFlags)
+	}
+}
+
+// Enabled implements slog.Handler.
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.core.Enabled(zapLevelFromSlog(level))
+}
+
+// Handle implements slog.Handler.
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	ent := zapcore.Entry{
+		Level:   zapLevelFromSlog(r.Level),
+		Time:    r.Time,
+		Message: r.Message,
+	}
+	ce := h.core.Check(ent, nil)
+	if ce == nil {
+		return nil
+	}
+	if h.addCaller && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ce.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+		ce.Caller.Function = frame.Function
+	}
+	if h.addStack != nil && h.addStack.Enabled(ent.Level) {
+		ce.Stack = stacktraceFrom(r.PC)
+	}
+
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	// nest the record attributes into the open groups, innermost first
+	for i := len(h.groups) - 1; i >= 0; i-- {
+		g := h.groups[i]
+		groupAttrs := append(g.attrs[:len(g.attrs):len(g.attrs)], attrs...)
+		attrs = []slog.Attr{{Key: g.name, Value: slog.GroupValue(groupAttrs...)}}
+	}
+
+	fields := GetFieldsFromContext(ctx)
+	fields = fields[:len(fields):len(fields)]
+	for _, a := range attrs {
+		fields = appendSlogAttr(fields, a)
+	}
+	ce.Write(fields...)
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	if len(h.groups) == 0 {
+		fields := make([]zap.Field, 0, len(attrs))
+		for _, a := range attrs {
+			fields = appendSlogAttr(fields, a)
+		}
+		h2.core = h.core.With(fields)
+		return &h2
+	}
+
+	h2.groups = append([]slogGroup(nil), h.groups...)
+	last := &h2.groups[len(h2.groups)-1]
+	last.attrs = append(last.attrs[:len(last.attrs):len(last.attrs)], attrs...)
+	return &h2
+}
+
+// WithGroup implements slog.Handler.
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], slogGroup{name: name})
+	return &h2
+}
+
+// zapLevelFromSlog maps slog levels, including custom ones in between, to the
+// closest zap level at or below them.
+func zapLevelFromSlog(l slog.Level) zapcore.Level {
+	switch {
+	case l >= slog.LevelError:
+		return zapcore.ErrorLevel
+	case l >= slog.LevelWarn:
+		return zapcore.WarnLevel
+	case l >= slog.LevelInfo:
+		return zapcore.InfoLevel
+	default:
+		return zapcore.DebugLevel
+	}
+}
+
+// appendSlogAttr converts a into zap fields, keeping the value type.
+func appendSlogAttr(fields []zap.Field, a slog.Attr) []zap.Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		if a.Key == "" {
+			for _, ga := range attrs {
+				fields = appendSlogAttr(fields, ga)
+			}
+			return fields
+		}
+		return append(fields, zap.Object(a.Key, slogGroupMarshaler(attrs)))
+	case slog.KindString:
+		return append(fields, zap.String(a.Key, a.Value.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(a.Key, a.Value.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(a.Key, a.Value.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(a.Key, a.Value.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(a.Key, a.Value.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(a.Key, a.Value.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(a.Key, a.Value.Time()))
+	default:
+		if err, ok := a.Value.Any().(error); ok {
+			return append(fields, zap.NamedError(a.Key, err))
+		}
+		return append(fields, zap.Any(a.Key, a.Value.Any()))
+	}
+}
+
+// slogGroupMarshaler encodes the attributes of a slog group as a zap object.
+type slogGroupMarshaler []slog.Attr
+
+func (m slogGroupMarshaler) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	var fields []zap.Field
+	for _, a := range m {
+		fields = appendSlogAttr(fields, a)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return nil
+}
+
+// stacktraceFrom formats the current goroutine's stack starting at the frame
+// identified by pc, in the same layout zap uses. If pc is not found on the
+// stack the whole stack, minus this function, is returned.
+func stacktraceFrom(pc uintptr) string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	pcs = pcs[:n]
+	for i, p := range pcs {
+		if p == pc {
+			pcs = pcs[i:]
+			break
+		}
+	}
+
+	var sb strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if sb.Len() > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(frame.Function)
+		sb.WriteString("\n\t")
+		sb.WriteString(frame.File)
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(frame.Line))
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,206 @@
+package logger
+
+import (
+	"context"
+	"encoding/base64"
+	"log/slog"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// slogLoggerKey holds the zap logger name on records sent to a slog.Handler.
+	slogLoggerKey = "logger"
+	// slogStacktraceKey holds the zap stack trace on records sent to a slog.Handler.
+	slogStacktraceKey = stacktraceKey
+)
+
+// slogCore is a zapcore.Core forwarding entries to a slog.Handler.
+type slogCore struct {
+	h slog.Handler
+}
+
+// NewSlogCore returns a zapcore.Core that forwards every entry to h, so zap
+// based code can log into a slog pipeline. The level is decided by h.Enabled,
+// the caller is passed as the record PC, the logger name and stack trace are
+// added as attributes, and zap namespaces become slog groups.
+func NewSlogCore(h slog.Handler) zapcore.Core {
+	return &slogCore{h: h}
+}
+
+func (c *slogCore) Enabled(lvl zapcore.Level) bool {
+	return c.h.Enabled(context.Background(), slogLevelFromZap(lvl))
+}
+
+func (c *slogCore) With(fields []zapcore.Field) zapcore.Core {
+	if len(fields) == 0 {
+		return c
+	}
+	enc := newSlogAttrEncoder()
+	for _, f := range fields {
+		enc.addField(f)
+	}
+
+	h := c.h
+	for i, g := range enc.groups {
+		if i > 0 {
+			h = h.WithGroup(g.name)
+		}
+		if len(g.attrs) > 0 {
+			h = h.WithAttrs(g.attrs)
+		}
+	}
+	return &slogCore{h: h}
+}
+
+func (c *slogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *slogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	r := slog.NewRecord(ent.Time, slogLevelFromZap(ent.Level), ent.Message, ent.Caller.PC)
+	if ent.LoggerName != "" {
+		r.AddAttrs(slog.String(slogLoggerKey, ent.LoggerName))
+	}
+	if ent.Stack != "" {
+		r.AddAttrs(slog.String(slogStacktraceKey, ent.Stack))
+	}
+
+	enc := newSlogAttrEncoder()
+	for _, f := range fields {
+		enc.addField(f)
+	}
+	r.AddAttrs(enc.attrs()...)
+
+	return c.h.Handle(context.Background(), r)
+}
+
+func (c *slogCore) Sync() error {
+	return nil
+}
+
+// slogLevelFromZap maps zap levels to slog levels. Levels above error have no
+// slog counterpart and are mapped to custom levels above slog.LevelError.
+func slogLevelFromZap(l zapcore.Level) slog.Level {
+	switch {
+	case l <= zapcore.DebugLevel:
+		return slog.LevelDebug
+	case l == zapcore.InfoLevel:
+		return slog.LevelInfo
+	case l == zapcore.WarnLevel:
+		return slog.LevelWarn
+	case l == zapcore.ErrorLevel:
+		return slog.LevelError
+	default:
+		// DPanic, Panic and Fatal
+		return slog.LevelError + slog.Level(l-zapcore.ErrorLevel)*2
+	}
+}
+
+// slogAttrEncoder is a zapcore.ObjectEncoder producing slog attributes.
+// OpenNamespace starts a new group that holds all following attributes.
+type slogAttrEncoder struct {
+	groups []slogGroup // groups[0] is the top level and has no name
+}
+
+func newSlogAttrEncoder() *slogAttrEncoder {
+	return &slogAttrEncoder{groups: []slogGroup{{}}}
+}
+
+// attrs returns the encoded attributes with namespaces nested as groups.
+func (e *slogAttrEncoder) attrs() []slog.Attr {
+	var inner []slog.Attr
+	for i := len(e.groups) - 1; i > 0; i-- {
+		g := e.groups[i]
+		attrs := append(g.attrs[:len(g.attrs):len(g.attrs)], inner...)
+		inner = []slog.Attr{slog.Attr{Key: g.name, Value: slog.GroupValue(attrs...)}}
+	}
+	top := e.groups[0].attrs
+	return append(top[:len(top):len(top)], inner...)
+}
+
+func (e *slogAttrEncoder) add(a slog.Attr) {
+	g := &e.groups[len(e.groups)-1]
+	g.attrs = append(g.attrs, a)
+}
+
+// addField encodes f, keeping errors as error values instead of strings.
+func (e *slogAttrEncoder) addField(f zapcore.Field) {
+	if f.Type == zapcore.ErrorType {
+		if err, ok := f.Interface.(error); ok {
+			e.add(slog.Any(f.Key, err))
+			return
+		}
+	}
+	f.AddTo(e)
+}
+
+func (e *slogAttrEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	// MapObjectEncoder turns arrays into []any keeping element types
+	m := zapcore.NewMapObjectEncoder()
+	err := m.AddArray(key, arr)
+	e.add(slog.Any(key, m.Fields[key]))
+	return err
+}
+
+func (e *slogAttrEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	inner := newSlogAttrEncoder()
+	err := obj.MarshalLogObject(inner)
+	e.add(slog.Attr{Key: key, Value: slog.GroupValue(inner.attrs()...)})
+	return err
+}
+
+func (e *slogAttrEncoder) AddBinary(key string, value []byte) {
+	e.add(slog.String(key, base64.StdEncoding.EncodeToString(value)))
+}
+
+func (e *slogAttrEncoder) AddByteString(key string, value []byte) {
+	e.add(slog.String(key, string(value)))
+}
+
+func (e *slogAttrEncoder) AddBool(key string, value bool) { e.add(slog.Bool(key, value)) }
+
+func (e *slogAttrEncoder) AddComplex128(key string, value complex128) {
+	e.add(slog.Any(key, value))
+}
+
+func (e *slogAttrEncoder) AddComplex64(key string, value complex64) {
+	e.add(slog.Any(key, value))
+}
+
+func (e *slogAttrEncoder) AddDuration(key string, value time.Duration) {
+	e.add(slog.Duration(key, value))
+}
+
+func (e *slogAttrEncoder) AddFloat64(key string, value float64) { e.add(slog.Float64(key, value)) }
+func (e *slogAttrEncoder) AddFloat32(key string, value float32) {
+	e.add(slog.Float64(key, float64(value)))
+}
+func (e *slogAttrEncoder) AddInt(key string, value int)        { e.add(slog.Int(key, value)) }
+func (e *slogAttrEncoder) AddInt64(key string, value int64)    { e.add(slog.Int64(key, value)) }
+func (e *slogAttrEncoder) AddInt32(key string, value int32)    { e.add(slog.Int64(key, int64(value))) }
+func (e *slogAttrEncoder) AddInt16(key string, value int16)    { e.add(slog.Int64(key, int64(value))) }
+func (e *slogAttrEncoder) AddInt8(key string, value int8)      { e.add(slog.Int64(key, int64(value))) }
+func (e *slogAttrEncoder) AddString(key, value string)         { e.add(slog.String(key, value)) }
+func (e *slogAttrEncoder) AddTime(key string, value time.Time) { e.add(slog.Time(key, value)) }
+func (e *slogAttrEncoder) AddUint(key string, value uint)      { e.add(slog.Uint64(key, uint64(value))) }
+func (e *slogAttrEncoder) AddUint64(key string, value uint64)  { e.add(slog.Uint64(key, value)) }
+func (e *slogAttrEncoder) AddUint32(key string, value uint32)  { e.add(slog.Uint64(key, uint64(value))) }
+func (e *slogAttrEncoder) AddUint16(key string, value uint16)  { e.add(slog.Uint64(key, uint64(value))) }
+func (e *slogAttrEncoder) AddUint8(key string, value uint8)    { e.add(slog.Uint64(key, uint64(value))) }
+func (e *slogAttrEncoder) AddUintptr(key string, value uintptr) {
+	e.add(slog.Uint64(key, uint64(value)))
+}
+
+func (e *slogAttrEncoder) AddReflected(key string, value any) error {
+	e.add(slog.Any(key, value))
+	return nil
+}
+
+func (e *slogAttrEncoder) OpenNamespace(key string) {
+	e.groups = append(e.groups, slogGroup{name: key})
+}
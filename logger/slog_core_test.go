@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/slogtest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSlogCoreFieldTypes(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewJSONHandler(&buf, &slog.HandlerOptions{AddSource: true, Level: slog.LevelDebug})
+	zl := zap.New(NewSlogCore(h), zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel)).Named("bridge")
+
+	ts := time.Date(2025, 6, 8, 18, 35, 28, 0, time.UTC)
+	zl.With(zap.String("service", "payment")).Error("failed",
+		zap.Int("n", 3),
+		zap.Uint8("u", 7),
+		zap.Float64("f", 1.5),
+		zap.Bool("b", true),
+		zap.Duration("d", time.Second),
+		zap.Time("t", ts),
+		zap.Error(errors.New("boom")),
+		zap.Strings("list", []string{"a", "b"}),
+		zap.Object("user", userMarshaler{id: 1}),
+		zap.Namespace("ns"),
+		zap.String("inner", "x"),
+	)
+
+	lines := decodeLines(t, buf.String())
+	require.Len(t, lines, 1)
+	line := lines[0]
+
+	assert.Equal(t, "ERROR", line["level"])
+	assert.Equal(t, "failed", line["msg"])
+	assert.Equal(t, "bridge", line[slogLoggerKey])
+	assert.Equal(t, "payment", line["service"])
+	assert.Equal(t, float64(3), line["n"])
+	assert.Equal(t, float64(7), line["u"])
+	assert.Equal(t, 1.5, line["f"])
+	assert.Equal(t, true, line["b"])
+	assert.Equal(t, float64(time.Second), line["d"])
+	assert.Equal(t, ts.Format(time.RFC3339), line["t"])
+	assert.Equal(t, "boom", line["error"])
+	assert.Equal(t, []any{"a", "b"}, line["list"])
+	assert.Equal(t, map[string]any{"id": float64(1), "role": "admin"}, line["user"])
+	assert.Equal(t, map[string]any{"inner": "x"}, line["ns"])
+
+	source, _ := line[slog.SourceKey].(map[string]any)
+	assert.True(t, strings.HasSuffix(source["file"].(string), "logger/slog_core_test.go"), source)
+	stack, _ := line[slogStacktraceKey].(string)
+	assert.Contains(t, stack, "TestSlogCoreFieldTypes")
+}
+
+func TestSlogCoreLevels(t *testing.T) {
+	var buf bytes.Buffer
+	core := NewSlogCore(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	assert.False(t, core.Enabled(zapcore.InfoLevel))
+	assert.True(t, core.Enabled(zapcore.WarnLevel))
+	assert.True(t, core.Enabled(zapcore.FatalLevel))
+
+	assert.Equal(t, slog.LevelDebug, slogLevelFromZap(zapcore.DebugLevel))
+	assert.Equal(t, slog.LevelError, slogLevelFromZap(zapcore.ErrorLevel))
+	assert.Greater(t, slogLevelFromZap(zapcore.FatalLevel), slogLevelFromZap(zapcore.PanicLevel))
+	// round trip keeps the standard levels
+	for _, l := range []zapcore.Level{zapcore.DebugLevel, zapcore.InfoLevel, zapcore.WarnLevel, zapcore.ErrorLevel} {
+		assert.Equal(t, l, zapLevelFromSlog(slogLevelFromZap(l)))
+	}
+}
+
+func TestSlogCoreNamespaceInWith(t *testing.T) {
+	var buf bytes.Buffer
+	zl := zap.New(NewSlogCore(slog.NewJSONHandler(&buf, nil)))
+
+	zl.With(zap.String("a", "b"), zap.Namespace("G"), zap.String("c", "d")).Info("msg", zap.String("e", "f"))
+
+	lines := decodeLines(t, buf.String(), slog.TimeKey)
+	require.Len(t, lines, 1)
+	assert.Equal(t, map[string]any{
+		"level": "INFO",
+		"msg":   "msg",
+		"a":     "b",
+		"G":     map[string]any{"c": "d", "e": "f"},
+	}, lines[0])
+}
+
+// TestSlogBridgeConformance runs slogtest against both adapters chained
+// together: slog -> zapcore.Core -> slog.JSONHandler.
+func TestSlogBridgeConformance(t *testing.T) {
+	var buf bytes.Buffer
+	core := NewSlogCore(slog.NewJSONHandler(&buf, nil))
+	h := NewSlogHandlerFromCore(core, SlogHandlerOptions{AddCaller: true})
+
+	err := slogtest.TestHandler(h, func() []map[string]any {
+		return decodeLines(t, buf.String())
+	})
+	assert.NoError(t, err)
+}
+
+func TestSlogHandlerFromCoreConformance(t *testing.T) {
+	l, buf := newBufferLogger(t)
+	h := NewSlogHandlerFromCore(l.GetLogger().Core(), SlogHandlerOptions{AddCaller: true})
+
+	err := slogtest.TestHandler(h, func() []map[string]any {
+		return decodeLines(t, buf.String())
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,198 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/slogtest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newBufferLogger returns a debug level Logger writing JSON lines into a buffer,
+// configured the same way NewLogger configures its file outputs.
+func newBufferLogger(t *testing.T) (*Logger, *bytes.Buffer) {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		TimeKey:        timeKey,
+		LevelKey:       levelKey,
+		MessageKey:     messageKey,
+		CallerKey:      callerKey,
+		StacktraceKey:  stacktraceKey,
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.RFC3339NanoTimeEncoder,
+		EncodeDuration: zapcore.SecondsDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}), zapcore.Lock(zapcore.AddSync(buf)), zapcore.DebugLevel)
+
+	zl := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(callerSkip), zap.AddStacktrace(zapcore.ErrorLevel))
+	return newRootLogger(&Config{}, zl), buf
+}
+
+// decodeLines decodes JSON lines, dropping the given volatile keys.
+func decodeLines(t *testing.T, data string, drop ...string) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+		if line == "" {
+			continue
+		}
+		m := map[string]any{}
+		require.NoError(t, json.Unmarshal([]byte(line), &m), line)
+		for _, k := range drop {
+			delete(m, k)
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+type userValuer struct{ id int }
+
+func (u userValuer) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int("id", u.id), slog.String("role", "admin"))
+}
+
+type userMarshaler struct{ id int }
+
+func (u userMarshaler) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt("id", u.id)
+	enc.AddString("role", "admin")
+	return nil
+}
+
+func TestSlogHandlerMatchesZap(t *testing.T) {
+	ts := time.Date(2025, 6, 8, 18, 35, 28, 0, time.UTC)
+	errBoom := errors.New("boom")
+
+	tests := []struct {
+		name  string
+		zapFn func(l *Logger)
+		slog  func(l *slog.Logger)
+	}{
+		{
+			name: "scalar types",
+			zapFn: func(l *Logger) {
+				l.Info("scalars", zap.String("s", "v"), zap.Int64("i", -3), zap.Uint64("u", 7),
+					zap.Float64("f", 1.5), zap.Bool("b", true), zap.Duration("d", 1500*time.Millisecond), zap.Time("t", ts))
+			},
+			slog: func(l *slog.Logger) {
+				l.Info("scalars", "s", "v", "i", -3, slog.Uint64("u", 7),
+					"f", 1.5, "b", true, "d", 1500*time.Millisecond, "t", ts)
+			},
+		},
+		{
+			name:  "levels",
+			zapFn: func(l *Logger) { l.Debug("d"); l.Warn("w") },
+			slog:  func(l *slog.Logger) { l.Debug("d"); l.Warn("w") },
+		},
+		{
+			name:  "error",
+			zapFn: func(l *Logger) { l.Info("failed", zap.NamedError("err", errBoom)) },
+			slog:  func(l *slog.Logger) { l.Info("failed", "err", errBoom) },
+		},
+		{
+			name:  "with attrs",
+			zapFn: func(l *Logger) { l.WithFields(zap.String("a", "b")).Info("m", zap.Int("k", 1)) },
+			slog:  func(l *slog.Logger) { l.With("a", "b").Info("m", "k", 1) },
+		},
+		{
+			name:  "groups",
+			zapFn: func(l *Logger) { l.Info("m", zap.Object("g", userMarshaler{id: 1})) },
+			slog:  func(l *slog.Logger) { l.WithGroup("g").With("id", 1).Info("m", "role", "admin") },
+		},
+		{
+			name:  "log valuer",
+			zapFn: func(l *Logger) { l.Info("m", zap.Object("user", userMarshaler{id: 2})) },
+			slog:  func(l *slog.Logger) { l.Info("m", "user", userValuer{id: 2}) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zl, zbuf := newBufferLogger(t)
+			tt.zapFn(zl)
+
+			sl, sbuf := newBufferLogger(t)
+			tt.slog(slog.New(NewSlogHandler(sl)))
+
+			assert.Equal(t,
+				decodeLines(t, zbuf.String(), timeKey, callerKey),
+				decodeLines(t, sbuf.String(), timeKey, callerKey))
+		})
+	}
+}
+
+func TestSlogHandlerCallerAndStacktrace(t *testing.T) {
+	l, buf := newBufferLogger(t)
+	slog.New(NewSlogHandler(l)).Error("failed")
+
+	lines := decodeLines(t, buf.String())
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0][callerKey], "logger/slog_test.go:")
+	stack, _ := lines[0][stacktraceKey].(string)
+	assert.True(t, strings.HasPrefix(stack, "github.com/double12gzh/zap-demo/logger.TestSlogHandlerCallerAndStacktrace"), stack)
+}
+
+func TestSlogHandlerContextFields(t *testing.T) {
+	l, buf := newBufferLogger(t)
+	ctx := StoreFieldsInContext(context.Background(), zap.String("X-Request-Id", "req-1"))
+
+	slog.New(NewSlogHandler(l)).WithGroup("g").InfoContext(ctx, "m", "k", "v")
+
+	lines := decodeLines(t, buf.String(), timeKey, callerKey)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "req-1", lines[0]["X-Request-Id"])
+	assert.Equal(t, map[string]any{"k": "v"}, lines[0]["g"])
+}
+
+func TestSlogHandlerLevels(t *testing.T) {
+	assert.Equal(t, zapcore.DebugLevel, zapLevelFromSlog(slog.LevelDebug))
+	assert.Equal(t, zapcore.DebugLevel, zapLevelFromSlog(slog.LevelInfo-1))
+	assert.Equal(t, zapcore.InfoLevel, zapLevelFromSlog(slog.LevelInfo))
+	assert.Equal(t, zapcore.InfoLevel, zapLevelFromSlog(slog.LevelInfo+2))
+	assert.Equal(t, zapcore.WarnLevel, zapLevelFromSlog(slog.LevelWarn))
+	assert.Equal(t, zapcore.ErrorLevel, zapLevelFromSlog(slog.LevelError))
+	assert.Equal(t, zapcore.ErrorLevel, zapLevelFromSlog(slog.LevelError+4))
+
+	dir := t.TempDir()
+	l, err := NewLogger(&Config{Level: LogLevelWarn, Filename: dir + "/app.log", ErrorFilename: dir + "/error.log"})
+	require.NoError(t, err)
+	h := NewSlogHandler(l)
+	assert.False(t, h.Enabled(context.Background(), slog.LevelInfo))
+	assert.True(t, h.Enabled(context.Background(), slog.LevelWarn))
+}
+
+func TestSlogHandlerConformance(t *testing.T) {
+	l, buf := newBufferLogger(t)
+	err := slogtest.TestHandler(NewSlogHandler(l), func() []map[string]any {
+		return decodeLines(t, buf.String())
+	})
+	assert.NoError(t, err)
+}
+
+func TestInstallSlogDefault(t *testing.T) {
+	l, buf := newBufferLogger(t)
+	prev := slog.Default()
+
+	restore := InstallSlogDefault(l)
+	slog.Info("through slog", "k", "v")
+	log.Print("through log")
+	restore()
+
+	assert.Same(t, prev, slog.Default())
+	lines := decodeLines(t, buf.String(), timeKey, callerKey)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "through slog", lines[0][messageKey])
+	assert.Equal(t, "through log", lines[1][messageKey])
+}
@@ -0,0 +1,22 @@
+package logger
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RedirectStdLog redirects the output of the standard library's package-global
+// logger to the global logger at the given level. The returned function restores
+// the previous output and flags.
+func RedirectStdLog(level LogLevel) (restore func(), err error) {
+	return redirectStdLog(GetLogger(), level)
+}
+
+func redirectStdLog(l *Logger, level LogLevel) (func(), error) {
+	lvl, err := zapcore.ParseLevel(level.String())
+	if err != nil {
+		return nil, err
+	}
+	// zap accounts for the log package frames itself, drop our wrapper skip
+	return zap.RedirectStdLogAt(l.logger.WithOptions(zap.AddCallerSkip(-callerSkip)), lvl)
+}
@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRedirectStdLog(t *testing.T) {
+	l, buf := newBufferLogger(t)
+	prevWriter := log.Writer()
+
+	restore, err := redirectStdLog(l, LogLevelWarn)
+	require.NoError(t, err)
+	log.Print("from std log")
+	restore()
+
+	assert.Equal(t, prevWriter, log.Writer())
+	lines := decodeLines(t, buf.String(), timeKey)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "warn", lines[0][levelKey])
+	assert.Equal(t, "from std log", lines[0][messageKey])
+	assert.Contains(t, lines[0][callerKey], "logger/stdlog_test.go:")
+
+	_, err = redirectStdLog(l, LogLevel("verbose"))
+	assert.Error(t, err)
+}
+
+func TestParseGinLine(t *testing.T) {
+	l, buf := newBufferLogger(t)
+	w := &ginWriter{logger: l.GetLogger().With(zap.String(ginComponentKey, ginComponent)), level: 0}
+
+	for _, line := range []string{
+		"[GIN-debug] GET    /ping                     --> github.com/double12gzh/zap-demo/router.ServHTTP.func2 (3 handlers)\n",
+		"[GIN-debug] [WARNING] Running in \"debug\" mode. Switch to \"release\" mode in production.\n - using env:\texport GIN_MODE=release\n\n",
+		"[GIN-debug] [ERROR] listen tcp :8080: bind: address already in use\n",
+		"[GIN] 2025/06/08 - 18:35:28 | 200 |     1.234ms |       127.0.0.1 | GET      \"/ping\"\n",
+		"[GIN] 2025/06/08 - 18:35:29 | 500 |       2.5ms |       127.0.0.1 | POST     \"/orders/\\\"x\\\"\"\nError #01: db \"orders\" unavailable\nError #02: retry failed\n\n",
+		"plain text\n",
+	} {
+		_, err := w.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	lines := decodeLines(t, buf.String(), timeKey, callerKey)
+	require.Len(t, lines, 6)
+
+	assert.Equal(t, map[string]any{
+		levelKey: "debug", messageKey: "route registered", ginComponentKey: ginComponent,
+		"method": "GET", "path": "/ping", "handler": "github.com/double12gzh/zap-demo/router.ServHTTP.func2", "handlers": float64(3),
+	}, lines[0])
+	assert.Equal(t, "warn", lines[1][levelKey])
+	assert.Contains(t, lines[1][messageKey], `Running in "debug" mode`)
+	assert.Equal(t, "error", lines[2][levelKey])
+	assert.Equal(t, "listen tcp :8080: bind: address already in use", lines[2][messageKey])
+	assert.Equal(t, "info", lines[3][levelKey])
+	assert.Equal(t, float64(200), lines[3]["status"])
+	assert.Equal(t, "/ping", lines[3]["path"])
+	assert.Equal(t, "127.0.0.1", lines[3]["client_ip"])
+	assert.Equal(t, 0.001234, lines[3]["latency"])
+	assert.NotContains(t, lines[3], "errors")
+	assert.Equal(t, float64(500), lines[4]["status"])
+	assert.Equal(t, `/orders/\"x\"`, lines[4]["path"])
+	assert.Equal(t, "Error #01: db \"orders\" unavailable\nError #02: retry failed", lines[4]["errors"])
+	assert.Equal(t, "plain text", lines[5][messageKey])
+}
+
+func TestInstallGinWriters(t *testing.T) {
+	l, buf := newBufferLogger(t)
+	prevMode := gin.Mode()
+	gin.SetMode(gin.DebugMode)
+	defer gin.SetMode(prevMode)
+	prevWriter := gin.DefaultWriter
+
+	restore := installGinWriters(l)
+	r := gin.New()
+	r.Use(gin.Logger())
+	r.GET("/ping", func(c *gin.Context) {
+		_ = c.Error(errors.New("cache miss"))
+		c.Status(http.StatusOK)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ping", nil))
+	restore()
+
+	assert.Equal(t, prevWriter, gin.DefaultWriter)
+
+	var route, access map[string]any
+	for _, line := range decodeLines(t, buf.String()) {
+		switch line[messageKey] {
+		case "route registered":
+			route = line
+		case "request":
+			access = line
+		}
+	}
+	require.NotNil(t, route)
+	assert.Equal(t, "/ping", route["path"])
+	assert.Equal(t, "GET", route["method"])
+	require.NotNil(t, access)
+	assert.Equal(t, float64(200), access["status"])
+	assert.Equal(t, "/ping", access["path"])
+	assert.Equal(t, "Error #01: cache miss", access["errors"])
+}
@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// TailBuffer keeps the low level entries of a single unit of work (usually a
+// request) in memory, so they can be written out only if the work fails.
+// When more than limit entries are captured the oldest ones are dropped.
+type TailBuffer struct {
+	mu      sync.Mutex
+	entries []bufferedEntry
+	start   int // index of the oldest entry once the ring is full
+	limit   int
+	dropped int
+}
+
+type bufferedEntry struct {
+	core   zapcore.Core
+	ent    zapcore.Entry
+	fields []zapcore.Field
+}
+
+// NewTailBuffer creates a TailBuffer holding at most limit entries.
+func NewTailBuffer(limit int) *TailBuffer {
+	if limit <= 0 {
+		limit = 1
+	}
+	return &TailBuffer{limit: limit}
+}
+
+func (b *TailBuffer) add(e bufferedEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.entries) < b.limit {
+		b.entries = append(b.entries, e)
+		return
+	}
+	b.entries[b.start] = e
+	b.start = (b.start + 1) % b.limit
+	b.dropped++
+}
+
+// take removes and returns the buffered entries in the order they were logged.
+func (b *TailBuffer) take() ([]bufferedEntry, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entries := append(b.entries[b.start:len(b.entries):len(b.entries)], b.entries[:b.start]...)
+	dropped := b.dropped
+	b.entries, b.start, b.dropped = nil, 0, 0
+	return entries, dropped
+}
+
+// Len returns the number of entries currently buffered.
+func (b *TailBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.entries)
+}
+
+// Dropped returns the number of entries dropped because the buffer was full.
+func (b *TailBuffer) Dropped() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped
+}
+
+// Flush writes the buffered entries to the logger's outputs in the order they
+// were logged and empties the buffer. It returns the number of entries written
+// and the number dropped because the buffer was full.
+func (b *TailBuffer) Flush() (written, dropped int) {
+	entries, dropped := b.take()
+	for _, e := range entries {
+		if ce := e.core.Check(e.ent, nil); ce != nil {
+			ce.Write(e.fields...)
+			written++
+		}
+	}
+	return written, dropped
+}
+
+// Discard empties the buffer without writing anything and returns the number of
+// entries that were discarded, including dropped ones.
+func (b *TailBuffer) Discard() int {
+	entries, dropped := b.take()
+	return len(entries) + dropped
+}
+
+// WithTailBuffer returns a logger that captures entries below the given level
+// into buf instead of writing them, while entries at or above level are written
+// immediately. Captured entries are kept down to debug level regardless of the
+// configured level, so that Flush reveals everything that happened.
+func (l *Logger) WithTailBuffer(buf *TailBuffer, level zapcore.Level) *Logger {
+	d := l.WithLevel(zapcore.DebugLevel)
+	base := d.base.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return &tailCore{Core: c, buf: buf, level: level}
+	}))
+	return d.derive(base, d.fields, base.With(d.fields...))
+}
+
+// tailCore captures entries below level into a TailBuffer.
+type tailCore struct {
+	zapcore.Core
+	buf   *TailBuffer
+	level zapcore.Level
+}
+
+func (c *tailCore) With(fields []zapcore.Field) zapcore.Core {
+	return &tailCore{Core: c.Core.With(fields), buf: c.buf, level: c.level}
+}
+
+func (c *tailCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= c.level {
+		return c.Core.Check(ent, ce)
+	}
+	if c.Core.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *tailCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	// the caller may reuse the fields slice after logging
+	c.buf.add(bufferedEntry{
+		core:   c.Core,
+		ent:    ent,
+		fields: append([]zapcore.Field(nil), fields...),
+	})
+	return nil
+}
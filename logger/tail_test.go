@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newFileLogger(t *testing.T) (*Logger, string) {
+	t.Helper()
+	dir := t.TempDir()
+	l, err := NewLogger(&Config{
+		Level:         LogLevelInfo,
+		Filename:      filepath.Join(dir, "app.log"),
+		ErrorFilename: filepath.Join(dir, "error.log"),
+	})
+	require.NoError(t, err)
+	return l, filepath.Join(dir, "app.log")
+}
+
+func readLines(t *testing.T, l *Logger, path string) []string {
+	t.Helper()
+	require.NoError(t, l.Sync())
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestTailBufferFlush(t *testing.T) {
+	l, path := newFileLogger(t)
+	buf := NewTailBuffer(10)
+	tl := l.WithFields(zap.String("req", "r1")).WithTailBuffer(buf, zapcore.WarnLevel)
+
+	tl.Debug("step 1")
+	tl.Info("step 2")
+	tl.Warn("warn passes through")
+	assert.Equal(t, 2, buf.Len())
+
+	lines := readLines(t, l, path)
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], "warn passes through")
+
+	written, dropped := buf.Flush()
+	assert.Equal(t, 2, written)
+	assert.Equal(t, 0, dropped)
+	assert.Equal(t, 0, buf.Len())
+
+	lines = readLines(t, l, path)
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[1], `"msg":"step 1","req":"r1"`)
+	assert.Contains(t, lines[2], `"msg":"step 2","req":"r1"`)
+}
+
+func TestTailBufferDiscard(t *testing.T) {
+	l, path := newFileLogger(t)
+	buf := NewTailBuffer(10)
+	tl := l.WithTailBuffer(buf, zapcore.WarnLevel)
+
+	tl.Info("never written")
+	assert.Equal(t, 1, buf.Discard())
+	assert.Empty(t, readLines(t, l, path))
+}
+
+func TestTailBufferLimitKeepsNewest(t *testing.T) {
+	l, path := newFileLogger(t)
+	buf := NewTailBuffer(3)
+	tl := l.WithTailBuffer(buf, zapcore.WarnLevel)
+
+	for _, msg := range []string{"m1", "m2", "m3", "m4", "m5"} {
+		tl.Info(msg)
+	}
+	assert.Equal(t, 2, buf.Dropped())
+
+	written, dropped := buf.Flush()
+	assert.Equal(t, 3, written)
+	assert.Equal(t, 2, dropped)
+
+	lines := readLines(t, l, path)
+	require.Len(t, lines, 3)
+	for i, msg := range []string{"m3", "m4", "m5"} {
+		assert.Contains(t, lines[i], `"msg":"`+msg+`"`)
+	}
+}
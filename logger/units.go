@@ -0,0 +1,214 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Size units, binary like the K, M and G suffixes accepted in configs.
+const (
+	KiB = 1 << (10 * (iota + 1))
+	MiB
+	GiB
+)
+
+// Day is the unit of Age.
+const Day = 24 * time.Hour
+
+// ByteSize is a number of bytes. It is written as a number with an optional
+// unit, 256KiB or 262144: B, K, KB, KiB, M, MB, MiB, G, GB and GiB are
+// accepted in any case, all binary, and a bare number is in bytes. It is
+// marshaled in the largest unit holding it exactly, such as 256KiB.
+type ByteSize int64
+
+// FileSize is the size of a log file in bytes. It is written like a ByteSize,
+// except that a bare number is in MiB for compatibility, and must be a whole
+// number of MiB.
+type FileSize int64
+
+// Duration is written as a number with a unit like 500ms, 1s or 2d, as
+// accepted by time.ParseDuration plus d for days. A bare number is in
+// milliseconds. It is marshaled in the form of time.Duration.String.
+type Duration time.Duration
+
+// Age is how long log files are kept. It is written like a Duration, except
+// that a bare number is in days for compatibility, and must be a whole number
+// of days. It is marshaled in days, such as 30d.
+type Age time.Duration
+
+func (s ByteSize) String() string { return formatBytes(int64(s)) }
+func (s FileSize) String() string { return formatBytes(int64(s)) }
+func (d Duration) String() string { return time.Duration(d).String() }
+
+func (a Age) String() string {
+	if a%Age(Day) == 0 {
+		return fmt.Sprintf("%dd", a/Age(Day))
+	}
+	return time.Duration(a).String()
+}
+
+// MiB returns s in MiB, rounded down.
+func (s FileSize) MiB() int { return int(s / MiB) }
+
+// Days returns a in days, rounded down.
+func (a Age) Days() int { return int(time.Duration(a) / Day) }
+
+func (s *ByteSize) UnmarshalText(text []byte) error {
+	n, err := parseBytes(string(text), 1)
+	*s = ByteSize(n)
+	return err
+}
+
+func (s *FileSize) UnmarshalText(text []byte) error {
+	n, err := parseBytes(string(text), MiB)
+	*s = FileSize(n)
+	return err
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := parseDuration(string(text), time.Millisecond)
+	*d = Duration(v)
+	return err
+}
+
+func (a *Age) UnmarshalText(text []byte) error {
+	v, err := parseDuration(string(text), Day)
+	*a = Age(v)
+	return err
+}
+
+func (s ByteSize) MarshalText() ([]byte, error) { return []byte(s.String()), nil }
+func (s FileSize) MarshalText() ([]byte, error) { return []byte(s.String()), nil }
+func (d Duration) MarshalText() ([]byte, error) { return []byte(d.String()), nil }
+func (a Age) MarshalText() ([]byte, error)      { return []byte(a.String()), nil }
+
+func (s *ByteSize) UnmarshalYAML(n *yaml.Node) error { return unmarshalYAMLText(n, s) }
+func (s *FileSize) UnmarshalYAML(n *yaml.Node) error { return unmarshalYAMLText(n, s) }
+func (d *Duration) UnmarshalYAML(n *yaml.Node) error { return unmarshalYAMLText(n, d) }
+func (a *Age) UnmarshalYAML(n *yaml.Node) error      { return unmarshalYAMLText(n, a) }
+
+func (s ByteSize) MarshalYAML() (any, error) { return s.String(), nil }
+func (s FileSize) MarshalYAML() (any, error) { return s.String(), nil }
+func (d Duration) MarshalYAML() (any, error) { return d.String(), nil }
+func (a Age) MarshalYAML() (any, error)      { return a.String(), nil }
+
+func (s *ByteSize) UnmarshalJSON(data []byte) error { return unmarshalJSONText(data, s) }
+func (s *FileSize) UnmarshalJSON(data []byte) error { return unmarshalJSONText(data, s) }
+func (d *Duration) UnmarshalJSON(data []byte) error { return unmarshalJSONText(data, d) }
+func (a *Age) UnmarshalJSON(data []byte) error      { return unmarshalJSONText(data, a) }
+
+func (s ByteSize) MarshalJSON() ([]byte, error) { return json.Marshal(s.String()) }
+func (s FileSize) MarshalJSON() ([]byte, error) { return json.Marshal(s.String()) }
+func (d Duration) MarshalJSON() ([]byte, error) { return json.Marshal(d.String()) }
+func (a Age) MarshalJSON() ([]byte, error)      { return json.Marshal(a.String()) }
+
+type textUnmarshaler interface {
+	UnmarshalText(text []byte) error
+}
+
+// unmarshalYAMLText decodes the scalar n with v.UnmarshalText. Errors are
+// returned as a *yaml.TypeError, so they are reported with the other invalid
+// values of the document instead of stopping the decoding.
+func unmarshalYAMLText(n *yaml.Node, v textUnmarshaler) error {
+	var s string
+	if err := n.Decode(&s); err != nil {
+		return err
+	}
+	if err := v.UnmarshalText([]byte(s)); err != nil {
+		return &yaml.TypeError{Errors: []string{fmt.Sprintf("line %d: %s", n.Line, err)}}
+	}
+	return nil
+}
+
+// unmarshalJSONText decodes a JSON string or number with v.UnmarshalText.
+func unmarshalJSONText(data []byte, v textUnmarshaler) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n json.Number
+		if json.Unmarshal(data, &n) != nil {
+			return fmt.Errorf("invalid value %s, expected a string or a number", data)
+		}
+		s = n.String()
+	}
+	return v.UnmarshalText([]byte(s))
+}
+
+// splitNumber splits s into its leading decimal number and the unit after it.
+func splitNumber(s string) (float64, string, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(c rune) bool {
+		return (c < '0' || c > '9') && c != '.' && c != '-' && c != '+'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || i == 0 {
+		return 0, "", fmt.Errorf("missing number in %q", s)
+	}
+	return n, strings.TrimSpace(s[i:]), nil
+}
+
+var byteUnits = map[string]int64{
+	"b": 1,
+	"k": KiB, "kb": KiB, "kib": KiB,
+	"m": MiB, "mb": MiB, "mib": MiB,
+	"g": GiB, "gb": GiB, "gib": GiB,
+}
+
+// parseBytes parses a size, bare numbers are multiplied by bare.
+func parseBytes(s string, bare int64) (int64, error) {
+	n, unit, err := splitNumber(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q, expected a number with an optional unit such as 256KiB", s)
+	}
+	mult := bare
+	if unit != "" {
+		var ok bool
+		if mult, ok = byteUnits[strings.ToLower(unit)]; !ok {
+			return 0, fmt.Errorf("invalid size %q, unknown unit %q, use B, KiB, MiB or GiB", s, unit)
+		}
+	}
+	size := n * float64(mult)
+	if size != float64(int64(size)) {
+		return 0, fmt.Errorf("invalid size %q, not a whole number of bytes", s)
+	}
+	return int64(size), nil
+}
+
+// parseDuration parses a duration, bare numbers are multiplied by bare.
+func parseDuration(s string, bare time.Duration) (time.Duration, error) {
+	n, unit, err := splitNumber(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, expected a number with a unit such as 500ms, 12h or 30d", s)
+	}
+	switch unit {
+	case "":
+		return time.Duration(n * float64(bare)), nil
+	case "d":
+		return time.Duration(n * float64(Day)), nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, expected a number with a unit such as 500ms, 12h or 30d", s)
+	}
+	return d, nil
+}
+
+// formatBytes writes n in the largest unit holding it exactly.
+func formatBytes(n int64) string {
+	for _, u := range []struct {
+		name string
+		size int64
+	}{{"GiB", GiB}, {"MiB", MiB}, {"KiB", KiB}} {
+		if n != 0 && n%u.size == 0 {
+			return fmt.Sprintf("%d%s", n/u.size, u.name)
+		}
+	}
+	return fmt.Sprintf("%dB", n)
+}
@@ -0,0 +1,164 @@
+package logger
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseUnits(t *testing.T) {
+	tests := []struct {
+		in   string
+		want any
+		err  string
+	}{
+		{in: "262144", want: ByteSize(256 * KiB)},
+		{in: "256KB", want: ByteSize(256 * KiB)},
+		{in: "256kib", want: ByteSize(256 * KiB)},
+		{in: "1.5K", want: ByteSize(1536)},
+		{in: "2 GiB", want: ByteSize(2 * GiB)},
+		{in: "0", want: ByteSize(0)},
+		{in: "big", want: ByteSize(0), err: `invalid size "big", expected a number with an optional unit such as 256KiB`},
+		{in: "10TB", want: ByteSize(0), err: `invalid size "10TB", unknown unit "TB", use B, KiB, MiB or GiB`},
+		{in: "0.5B", want: ByteSize(0), err: `invalid size "0.5B", not a whole number of bytes`},
+
+		{in: "100", want: FileSize(100 * MiB)},
+		{in: "100MiB", want: FileSize(100 * MiB)},
+		{in: "1G", want: FileSize(GiB)},
+
+		{in: "1000", want: Duration(time.Second)},
+		{in: "500ms", want: Duration(500 * time.Millisecond)},
+		{in: "12h", want: Duration(12 * time.Hour)},
+		{in: "1d", want: Duration(Day)},
+		{in: "soon", want: Duration(0), err: `invalid duration "soon", expected a number with a unit such as 500ms, 12h or 30d`},
+
+		{in: "30", want: Age(30 * Day)},
+		{in: "30d", want: Age(30 * Day)},
+		{in: "48h", want: Age(2 * Day)},
+		{in: "1x", want: Age(0), err: `invalid duration "1x", expected a number with a unit such as 500ms, 12h or 30d`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := unmarshalTextAs(tt.want, tt.in)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// unmarshalTextAs parses s into a new value of the type of like.
+func unmarshalTextAs(like any, s string) (any, error) {
+	switch like.(type) {
+	case ByteSize:
+		var v ByteSize
+		err := v.UnmarshalText([]byte(s))
+		return v, err
+	case FileSize:
+		var v FileSize
+		err := v.UnmarshalText([]byte(s))
+		return v, err
+	case Duration:
+		var v Duration
+		err := v.UnmarshalText([]byte(s))
+		return v, err
+	default:
+		var v Age
+		err := v.UnmarshalText([]byte(s))
+		return v, err
+	}
+}
+
+func TestUnitsString(t *testing.T) {
+	assert.Equal(t, "256KiB", ByteSize(256*KiB).String())
+	assert.Equal(t, "1536B", ByteSize(1536).String())
+	assert.Equal(t, "0B", ByteSize(0).String())
+	assert.Equal(t, "1GiB", FileSize(1024*MiB).String())
+	assert.Equal(t, "1.5s", Duration(1500*time.Millisecond).String())
+	assert.Equal(t, "30d", Age(30*Day).String())
+	assert.Equal(t, "12h0m0s", Age(12*time.Hour).String())
+}
+
+func TestConfigUnitsRoundTrip(t *testing.T) {
+	config := mergeConfigWithDefault(&Config{
+		MaxSize:            Ptr[FileSize](2 * GiB),
+		MaxAge:             Ptr(Age(7 * Day)),
+		BufferSize:         Ptr[ByteSize](0),
+		AsyncFlushInterval: Ptr(Duration(250 * time.Millisecond)),
+	})
+
+	out, err := yaml.Marshal(YamlConfig{Logger: *config})
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "max_size: 2GiB\n")
+	assert.Contains(t, string(out), "max_age: 7d\n")
+	assert.Contains(t, string(out), "buffer_size: 0B\n")
+	assert.Contains(t, string(out), "async_buffer_size: 256KiB\n")
+	assert.Contains(t, string(out), "async_flush_interval: 250ms\n")
+
+	var fromYaml YamlConfig
+	require.NoError(t, yaml.Unmarshal(out, &fromYaml))
+	assert.Equal(t, config, &fromYaml.Logger)
+
+	data, err := json.Marshal(config)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"max_size":"2GiB"`)
+	var fromJSON Config
+	require.NoError(t, json.Unmarshal(data, &fromJSON))
+	assert.Equal(t, config, &fromJSON)
+}
+
+func TestConfigUnitsJSONNumbers(t *testing.T) {
+	var c Config
+	require.NoError(t, json.Unmarshal([]byte(`{"max_size": 100, "max_age": "30d", "buffer_size": 4096, "async_flush_interval": 500}`), &c))
+	assert.Equal(t, Ptr[FileSize](100*MiB), c.MaxSize)
+	assert.Equal(t, Ptr(Age(30*Day)), c.MaxAge)
+	assert.Equal(t, Ptr[ByteSize](4096), c.BufferSize)
+	assert.Equal(t, Ptr(Duration(500*time.Millisecond)), c.AsyncFlushInterval)
+
+	assert.EqualError(t, json.Unmarshal([]byte(`{"max_size": true}`), &c), "invalid value true, expected a string or a number")
+}
+
+func TestLoadConfigFromYamlUnits(t *testing.T) {
+	path := writeConfig(t, `
+logger:
+  filename: $DIR/app.log
+  max_size: 512KiB
+  max_age: 12h
+  buffer_size: 64KB
+  async_flush_interval: 2s
+`)
+	_, err := LoadConfigFromYaml(path)
+	var invalid *ValidationError
+	require.ErrorAs(t, err, &invalid)
+	var got []string
+	for _, fe := range invalid.Errors {
+		got = append(got, fe.Error())
+	}
+	assert.Equal(t, []string{
+		"logger.max_size (line 4, column 13): must be a whole number of MiB, got 512KiB",
+		"logger.max_age (line 5, column 12): must be a whole number of days, got 12h0m0s",
+	}, got)
+
+	path = writeConfig(t, `
+logger:
+  filename: $DIR/app.log
+  max_size: 1GiB
+  max_age: 14d
+  buffer_size: 64KB
+  async_flush_interval: 2s
+`)
+	config, err := LoadConfigFromYaml(path)
+	require.NoError(t, err)
+	assert.Equal(t, Ptr[FileSize](GiB), config.MaxSize)
+	assert.Equal(t, 1024, config.MaxSize.MiB())
+	assert.Equal(t, 14, config.MaxAge.Days())
+	assert.Equal(t, Ptr[ByteSize](64*KiB), config.BufferSize)
+	assert.Equal(t, Ptr(Duration(2*time.Second)), config.AsyncFlushInterval)
+}
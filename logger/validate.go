@@ -0,0 +1,373 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError describes one invalid value of a config.
+type FieldError struct {
+	Path string // YAML path of the value, such as logger.max_size
+	// File is the included or profile file holding the value, empty for the
+	// config file itself.
+	File    string
+	Line    int // line of the value in File, 0 when unknown
+	Column  int
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	switch {
+	case e.Line > 0 && e.File != "":
+		return fmt.Sprintf("%s (%s line %d, column %d): %s", e.Path, e.File, e.Line, e.Column, e.Message)
+	case e.Line > 0:
+		return fmt.Sprintf("%s (line %d, column %d): %s", e.Path, e.Line, e.Column, e.Message)
+	case e.Path != "":
+		return fmt.Sprintf("%s: %s", e.Path, e.Message)
+	default:
+		return e.Message
+	}
+}
+
+// ValidationError reports every problem found in a config at once.
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return "invalid logger config: " + e.Errors[0].Error()
+	}
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = "\t" + fe.Error()
+	}
+	return fmt.Sprintf("invalid logger config, %d problems:\n%s", len(e.Errors), strings.Join(msgs, "\n"))
+}
+
+// Validate checks the values of c and reports every invalid one in a
+// *ValidationError. Paths are the YAML keys of the fields. Unset values are
+// valid, they are replaced by defaults when the logger is created.
+func (c *Config) Validate() error {
+	var errs []*FieldError
+	if c.Level != "" {
+		if _, err := zapcore.ParseLevel(c.Level.String()); err != nil {
+			errs = append(errs, &FieldError{
+				Path:    "level",
+				Message: fmt.Sprintf("unknown level %q, expected one of debug, info, warn, error, panic, fatal", c.Level),
+			})
+		}
+	}
+	for _, f := range []optionalNumber{
+		newOptionalNumber("max_size", c.MaxSize, true),
+		newOptionalNumber("max_backups", c.MaxBackups, false),
+		newOptionalNumber("max_age", c.MaxAge, false),
+		newOptionalNumber("buffer_size", c.BufferSize, false),
+		newOptionalNumber("async_buffer_size", c.AsyncBufferSize, true),
+		newOptionalNumber("async_flush_interval", c.AsyncFlushInterval, true),
+	} {
+		switch {
+		case !f.set:
+		case f.positive && f.n <= 0:
+			errs = append(errs, &FieldError{Path: f.path, Message: fmt.Sprintf("must be positive, got %s", f.text)})
+		case f.n < 0:
+			errs = append(errs, &FieldError{Path: f.path, Message: fmt.Sprintf("must not be negative, got %s", f.text)})
+		}
+	}
+	// the rotation settings are whole MiB and days
+	if c.MaxSize != nil && *c.MaxSize > 0 && *c.MaxSize%MiB != 0 {
+		errs = append(errs, &FieldError{Path: "max_size", Message: fmt.Sprintf("must be a whole number of MiB, got %s", c.MaxSize)})
+	}
+	if c.MaxAge != nil && *c.MaxAge > 0 && *c.MaxAge%Age(Day) != 0 {
+		errs = append(errs, &FieldError{Path: "max_age", Message: fmt.Sprintf("must be a whole number of days, got %s", c.MaxAge)})
+	}
+	errs = append(errs, c.Encoder.validate()...)
+	// the keys of the entry depend on the preset
+	errs = append(errs, validateFields(c.Fields, mergeConfigWithDefault(c).Encoder.entryKeys())...)
+	return newValidationError(errs)
+}
+
+// optionalNumber is a numeric Config value checked by Validate.
+type optionalNumber struct {
+	path     string
+	set      bool
+	n        int64
+	text     string // n with its unit
+	positive bool   // 0 has no meaning
+}
+
+func newOptionalNumber[T ~int | ~int64](path string, v *T, positive bool) optionalNumber {
+	if v == nil {
+		return optionalNumber{path: path}
+	}
+	return optionalNumber{path: path, set: true, n: int64(*v), text: fmt.Sprint(*v), positive: positive}
+}
+
+// checkWritable reports the log files of c whose directory cannot be written.
+func (c *Config) checkWritable() []*FieldError {
+	var errs []*FieldError
+	for _, f := range []struct {
+		path     string
+		filename string
+	}{
+		{"filename", c.Filename},
+		{"error_filename", c.ErrorFilename},
+	} {
+		if f.filename == "" {
+			continue
+		}
+		if err := checkWritableDir(filepath.Dir(f.filename)); err != nil {
+			errs = append(errs, &FieldError{Path: f.path, Message: err.Error()})
+		}
+	}
+	return errs
+}
+
+// checkWritableDir reports whether files can be created in dir. A missing dir
+// is fine as long as it can be created in its closest existing parent.
+func checkWritableDir(dir string) error {
+	existing := dir
+	for {
+		info, err := os.Stat(existing)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("log directory %s: %s is not a directory", dir, existing)
+			}
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("log directory %s: %w", dir, err)
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		existing = parent
+	}
+
+	f, err := os.CreateTemp(existing, ".logger-write-check-*")
+	if err != nil {
+		return fmt.Errorf("log directory %s is not writable: %w", dir, err)
+	}
+	name := f.Name()
+	_ = f.Close()
+	return os.Remove(name)
+}
+
+func newValidationError(errs []*FieldError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
+
+// yamlPositions maps YAML paths such as logger.max_size to their value node.
+type yamlPositions map[string]*yaml.Node
+
+// locate prefixes the Config paths of errs and sets their position, or names
+// the environment variable that set the value.
+func (p yamlPositions) locate(prefix string, errs []*FieldError, sources ConfigSources, files nodeFiles) []*FieldError {
+	for _, fe := range errs {
+		if env := sources[fe.Path].Env; env != "" {
+			fe.Message = env + ": " + fe.Message
+		}
+		if prefix != "" {
+			fe.Path = prefix + "." + fe.Path
+		}
+		if n, ok := p[fe.Path]; ok && fe.Line == 0 && n.Line > 0 {
+			files.position(fe, n)
+		}
+	}
+	return errs
+}
+
+// pathAt returns the path of the value at line, or "" when there is none.
+func (p yamlPositions) pathAt(line int) string {
+	path := ""
+	for k, n := range p {
+		// the innermost value on the line has the longest path
+		if n.Line == line && len(k) > len(path) {
+			path = k
+		}
+	}
+	return path
+}
+
+// checkKeys walks the YAML node against the type it is decoded into, records
+// the position of every known key and reports the unknown ones with a
+// suggestion when a known key is close enough.
+func checkKeys(n *yaml.Node, t reflect.Type, path string, pos yamlPositions, files nodeFiles) []*FieldError {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if t.Kind() != reflect.Struct || n.Kind != yaml.MappingNode || reflect.PointerTo(t).Implements(yamlUnmarshalerType) {
+		return nil
+	}
+
+	fields := yamlFields(t)
+	var errs []*FieldError
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		keyPath := joinPath(path, key.Value)
+		f, ok := fields[key.Value]
+		if !ok {
+			msg := fmt.Sprintf("unknown key %q", key.Value)
+			if s := suggest(key.Value, fields); s != "" {
+				msg += fmt.Sprintf(", did you mean %q?", s)
+			}
+			errs = append(errs, files.errorAt(key, keyPath, msg))
+			continue
+		}
+		pos[keyPath] = value
+		errs = append(errs, checkKeys(value, f.Type, keyPath, pos, files)...)
+	}
+	return errs
+}
+
+var yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// yamlFields returns the fields of struct type t by YAML key.
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(opts, "inline") {
+			for k, v := range yamlFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// suggest returns the known key closest to key, or "" when none is close.
+func suggest(key string, fields map[string]reflect.StructField) string {
+	best, bestDist := "", len(key)/3+2
+	for known := range fields {
+		if d := levenshtein(key, known); d < bestDist || (d == bestDist && best != "" && known < best) {
+			best, bestDist = known, d
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// yamlLineRe matches the "line N: " prefix of the messages of a yaml.TypeError.
+var yamlLineRe = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// typeErrors converts the messages of a yaml.TypeError into FieldErrors.
+func typeErrors(err *yaml.TypeError, pos yamlPositions) []*FieldError {
+	errs := make([]*FieldError, 0, len(err.Errors))
+	for _, msg := range err.Errors {
+		fe := &FieldError{Message: msg}
+		if m := yamlLineRe.FindStringSubmatch(msg); m != nil {
+			fe.Line, _ = strconv.Atoi(m[1])
+			fe.Message = m[2]
+			fe.Path = pos.pathAt(fe.Line)
+			if n, ok := pos[fe.Path]; ok {
+				fe.Column = n.Column
+			}
+		}
+		errs = append(errs, fe)
+	}
+	return errs
+}
+
+// leafTypeErrors decodes each value of the logger section on its own and
+// reports the ones of the wrong type. Unlike the line of a yaml.TypeError,
+// this tells which file the value comes from.
+func leafTypeErrors(pos yamlPositions, files nodeFiles) []*FieldError {
+	var errs []*FieldError
+	for _, leaf := range configLeaves(reflect.TypeOf(Config{}), "") {
+		path := "logger." + leaf.path
+		n, ok := pos[path]
+		if !ok || n.Line == 0 {
+			// unset or set from the environment, which is checked already
+			continue
+		}
+		if err := n.Decode(reflect.New(leaf.typ).Interface()); err != nil {
+			errs = append(errs, files.errorAt(n, path, yamlErrorMessage(err)))
+		}
+	}
+	return errs
+}
+
+// hasFieldError reports whether errs has an error for path.
+func hasFieldError(errs []*FieldError, path string) bool {
+	for _, fe := range errs {
+		if fe.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// sortFieldErrors orders errs by file, the config file first, and position,
+// errors without one last.
+func sortFieldErrors(errs []*FieldError) {
+	sort.SliceStable(errs, func(i, j int) bool {
+		li, lj := errs[i].Line, errs[j].Line
+		if li == 0 || lj == 0 {
+			return li != 0 && lj == 0
+		}
+		if fi, fj := errs[i].File, errs[j].File; fi != fj {
+			return fi == "" || (fj != "" && fi < fj)
+		}
+		if li != lj {
+			return li < lj
+		}
+		return errs[i].Column < errs[j].Column
+	})
+}
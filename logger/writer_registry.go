@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// ErrRotationConflict is returned by NewLogger when a log file is already open
+// in the process with different rotation settings.
+var ErrRotationConflict = errors.New("conflicting rotation settings")
+
+// writers holds the log files opened by all loggers of the process. Loggers
+// writing to the same file share one lumberjack.Logger, otherwise each one
+// would rotate the file on its own and they would rename it under each other.
+var writers = writerRegistry{files: map[string]*sharedWriter{}}
+
+type writerRegistry struct {
+	mu    sync.Mutex
+	files map[string]*sharedWriter // keyed by absolute path
+}
+
+// rotation holds the settings that must agree between loggers sharing a file.
+type rotation struct {
+	maxSize    int
+	maxBackups int
+	maxAge     int
+	compress   bool
+}
+
+func (r rotation) String() string {
+	return fmt.Sprintf("max_size=%d max_backups=%d max_age=%d compress=%t", r.maxSize, r.maxBackups, r.maxAge, r.compress)
+}
+
+// sharedWriter is a reference counted log file. lumberjack serializes Write,
+// Rotate and Close, so it can be used by several loggers at once.
+type sharedWriter struct {
+	*lumberjack.Logger
+	path     string
+	rotation rotation
+	refs     int // guarded by writers.mu
+}
+
+// openWriter returns the writer of filename, creating it on first use. Every
+// successful call must be paired with a call to release.
+func openWriter(filename string, config *Config) (*sharedWriter, error) {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	r := rotation{
+		maxSize:    config.MaxSize.MiB(),
+		maxBackups: *config.MaxBackups,
+		maxAge:     config.MaxAge.Days(),
+		compress:   *config.Compress,
+	}
+
+	writers.mu.Lock()
+	defer writers.mu.Unlock()
+
+	if w, ok := writers.files[path]; ok {
+		if w.rotation != r {
+			return nil, fmt.Errorf("log file %s: %w: already open with %s, requested %s", path, ErrRotationConflict, w.rotation, r)
+		}
+		w.refs++
+		return w, nil
+	}
+
+	w := &sharedWriter{
+		Logger: &lumberjack.Logger{
+			Filename:   path,
+			MaxSize:    r.maxSize,
+			MaxBackups: r.maxBackups,
+			MaxAge:     r.maxAge,
+			Compress:   r.compress,
+			LocalTime:  true,
+		},
+		path:     path,
+		rotation: r,
+		refs:     1,
+	}
+	writers.files[path] = w
+	return w, nil
+}
+
+// release drops one reference and closes the file when it was the last one.
+func (w *sharedWriter) release() error {
+	writers.mu.Lock()
+	defer writers.mu.Unlock()
+
+	if w.refs == 0 {
+		return nil
+	}
+	w.refs--
+	if w.refs > 0 {
+		return nil
+	}
+	delete(writers.files, w.path)
+	return w.Close()
+}
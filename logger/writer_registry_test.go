@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSharedWriter(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	// relative and absolute names of the same file share one writer
+	first, err := NewLogger(&Config{Filename: "app.log", ErrorFilename: "error.log"})
+	require.NoError(t, err)
+	second, err := NewLogger(&Config{Filename: filepath.Join(dir, "app.log"), ErrorFilename: filepath.Join(dir, "other.log")})
+	require.NoError(t, err)
+
+	assert.Same(t, first.writers[0], second.writers[0])
+	assert.NotSame(t, first.writers[1], second.writers[1])
+	assert.Equal(t, 2, first.writers[0].refs)
+
+	// closing one logger keeps the file open for the other
+	first.Info("from first")
+	require.NoError(t, first.Close(context.Background()))
+	second.Info("from second")
+	require.NoError(t, second.Sync())
+
+	data, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "from first")
+	assert.Contains(t, string(data), "from second")
+
+	require.NoError(t, second.Close(context.Background()))
+	writers.mu.Lock()
+	defer writers.mu.Unlock()
+	assert.NotContains(t, writers.files, filepath.Join(dir, "app.log"))
+	assert.NotContains(t, writers.files, filepath.Join(dir, "error.log"))
+}
+
+func TestSharedWriterConflict(t *testing.T) {
+	dir := t.TempDir()
+	first, err := NewLogger(&Config{Filename: filepath.Join(dir, "app.log"), ErrorFilename: filepath.Join(dir, "error.log")})
+	require.NoError(t, err)
+	defer first.Close(context.Background())
+
+	_, err = NewLogger(&Config{Filename: filepath.Join(dir, "app.log"), ErrorFilename: filepath.Join(dir, "other.log"), MaxSize: Ptr[FileSize](MiB)})
+	require.ErrorIs(t, err, ErrRotationConflict)
+	assert.Contains(t, err.Error(), filepath.Join(dir, "app.log"))
+	assert.Contains(t, err.Error(), "max_size=100")
+	assert.Contains(t, err.Error(), "max_size=1 ")
+
+	// the failed logger released what it had opened before the conflict
+	writers.mu.Lock()
+	defer writers.mu.Unlock()
+	assert.Equal(t, 1, writers.files[filepath.Join(dir, "app.log")].refs)
+}
+
+func TestSharedWriterRotateUnderLoad(t *testing.T) {
+	dir := t.TempDir()
+	config := func() *Config {
+		return &Config{
+			Filename:      filepath.Join(dir, "app.log"),
+			ErrorFilename: filepath.Join(dir, "error.log"),
+			MaxBackups:    Ptr(1000),
+			MaxAge:        Ptr(Age(365 * Day)),
+			Compress:      Ptr(false), // the entries are read back from the backups
+			Console:       Ptr(false),
+		}
+	}
+	loggers := make([]*Logger, 2)
+	for i := range loggers {
+		l, err := NewLogger(config())
+		require.NoError(t, err)
+		loggers[i] = l
+	}
+
+	const writersPerLogger, linesPerWriter = 4, 500
+	var wg sync.WaitGroup
+	for i, l := range loggers {
+		for j := 0; j < writersPerLogger; j++ {
+			wg.Add(1)
+			go func(l *Logger, id int) {
+				defer wg.Done()
+				for n := 0; n < linesPerWriter; n++ {
+					l.Info("load", zap.Int("writer", id), zap.Int("n", n))
+				}
+			}(l, i*writersPerLogger+j)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	rotations := 0
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+			require.NoError(t, loggers[rotations%2].Rotate())
+			rotations++
+			// backups are named by millisecond, do not rotate twice in one
+			time.Sleep(2 * time.Millisecond)
+		}
+	}
+	for _, l := range loggers {
+		require.NoError(t, l.Close(context.Background()))
+	}
+
+	// every entry is found exactly once, as a complete line
+	seen := map[[2]int]bool{}
+	files, err := filepath.Glob(filepath.Join(dir, "app*.log"))
+	require.NoError(t, err)
+	for _, name := range files {
+		f, err := os.Open(name)
+		require.NoError(t, err)
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var entry struct {
+				Writer int `json:"writer"`
+				N      int `json:"n"`
+			}
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry), "%s: %s", name, scanner.Text())
+			key := [2]int{entry.Writer, entry.N}
+			assert.False(t, seen[key], "duplicate entry %v", key)
+			seen[key] = true
+		}
+		require.NoError(t, scanner.Err())
+		require.NoError(t, f.Close())
+	}
+	assert.Len(t, seen, len(loggers)*writersPerLogger*linesPerWriter)
+	t.Logf("%d rotations, %d files", rotations, len(files))
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/double12gzh/zap-demo/logger"
 	"github.com/double12gzh/zap-demo/router"
@@ -13,5 +14,8 @@ func init() {
 
 func main() {
 	fmt.Println("main")
-	router.ServHTTP()
+	if err := router.ServHTTP(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/double12gzh/zap-demo/logger"
+)
+
+const (
+	// DebugLogHeader 取值为 "1" 时请求为本次请求开启 debug 日志
+	DebugLogHeader = "X-Debug-Log"
+	// DebugLogSignatureHeader 携带 HMACSigned 校验使用的签名, 格式为 "<unix 秒>.<nonce>.<hex 签名>"
+	DebugLogSignatureHeader = "X-Debug-Log-Signature"
+)
+
+// DebugAuthorizer 判断请求是否有权开启 debug 日志
+type DebugAuthorizer func(c *gin.Context) bool
+
+// WithDebugLogging 允许通过 DebugLogHeader 为单个请求开启 debug 日志.
+// 只有 auth 通过的请求才会生效, 开启与拒绝都会记录审计日志
+func WithDebugLogging(auth DebugAuthorizer) Option {
+	return func(o *options) {
+		o.debugAuth = auth
+	}
+}
+
+// AllowCIDRs 返回一个按对端 IP 放行的 DebugAuthorizer.
+// 使用 TCP 连接的对端地址而不是 X-Forwarded-For, 避免被伪造
+func AllowCIDRs(cidrs ...string) (DebugAuthorizer, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid debug log cidr %q: %w", cidr, err)
+		}
+		nets = append(nets, n)
+	}
+
+	return func(c *gin.Context) bool {
+		ip := net.ParseIP(c.RemoteIP())
+		if ip == nil {
+			return false
+		}
+		for _, n := range nets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// HMACSigned 返回一个校验 DebugLogSignatureHeader 的 DebugAuthorizer.
+// 签名为 hex(HMAC-SHA256(secret, "<unix 秒>:<nonce>:<method>:<path>")), 时间戳与当前时间相差超过 maxSkew 时拒绝.
+// 每个 nonce 只能使用一次: 校验通过的 nonce 会被记住到其时间戳超出 maxSkew 为止,
+// 因此截获的签名无法在有效期内重放
+func HMACSigned(secret []byte, maxSkew time.Duration) DebugAuthorizer {
+	var (
+		mu   sync.Mutex
+		seen = map[string]time.Time{} // nonce -> 签名失效的时间
+	)
+	return func(c *gin.Context) bool {
+		parts := strings.Split(c.GetHeader(DebugLogSignatureHeader), ".")
+		if len(parts) != 3 || parts[1] == "" {
+			return false
+		}
+		ts, nonce, sig := parts[0], parts[1], parts[2]
+		unix, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return false
+		}
+		signed, now := time.Unix(unix, 0), time.Now()
+		if skew := now.Sub(signed); skew > maxSkew || skew < -maxSkew {
+			return false
+		}
+		got, err := hex.DecodeString(sig)
+		if err != nil {
+			return false
+		}
+		if !hmac.Equal(got, signDebugLog(secret, ts, nonce, c.Request.Method, c.Request.URL.Path)) {
+			return false
+		}
+
+		// 只记录签名正确的 nonce, 数量受有效期内签发的签名数限制
+		mu.Lock()
+		defer mu.Unlock()
+		for n, expires := range seen {
+			if now.After(expires) {
+				delete(seen, n)
+			}
+		}
+		if _, ok := seen[nonce]; ok {
+			return false
+		}
+		seen[nonce] = signed.Add(maxSkew)
+		return true
+	}
+}
+
+// SignDebugLog 生成 HMACSigned 可以校验通过的 DebugLogSignatureHeader 取值,
+// 格式为 "<unix 秒>.<nonce>.<hex 签名>", 每次调用使用新的随机 nonce, 只能用于一次请求
+func SignDebugLog(secret []byte, now time.Time, method, path string) string {
+	ts := strconv.FormatInt(now.Unix(), 10)
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+	n := hex.EncodeToString(nonce)
+	return ts + "." + n + "." + hex.EncodeToString(signDebugLog(secret, ts, n, method, path))
+}
+
+func signDebugLog(secret []byte, ts, nonce, method, path string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts + ":" + nonce + ":" + method + ":" + path))
+	return mac.Sum(nil)
+}
+
+// AnyOf 只要任意一个 DebugAuthorizer 通过即放行
+func AnyOf(auths ...DebugAuthorizer) DebugAuthorizer {
+	return func(c *gin.Context) bool {
+		for _, auth := range auths {
+			if auth(c) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// debugLogger 根据 DebugLogHeader 返回本次请求使用的 logger
+func (o *options) debugLogger(c *gin.Context, l *logger.Logger) *logger.Logger {
+	if o.debugAuth == nil || c.GetHeader(DebugLogHeader) != "1" {
+		return l
+	}
+
+	audit := []zap.Field{
+		zap.String("remote_ip", c.RemoteIP()),
+		zap.String("method", c.Request.Method),
+		zap.String("path", c.Request.URL.Path),
+	}
+	if !o.debugAuth(c) {
+		l.Warn("request debug logging rejected", audit...)
+		return l
+	}
+
+	l = l.WithLevel(zapcore.DebugLevel)
+	l.Info("request debug logging enabled", audit...)
+	return l
+}
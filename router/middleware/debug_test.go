@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/double12gzh/zap-demo/logger"
+)
+
+func TestDebugLoggingPerRequest(t *testing.T) {
+	auth, err := AllowCIDRs("192.0.2.0/24")
+	require.NoError(t, err)
+
+	r := gin.New()
+	r.Use(RequestIDMiddleware(WithDebugLogging(auth)))
+	r.GET("/", func(c *gin.Context) {
+		logger.FromContext(c.Request.Context()).Debug("per request debug line")
+		c.Status(http.StatusOK)
+	})
+
+	// 并发请求中只有携带 header 的请求输出 debug 日志
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(RequestIDHeader, fmt.Sprintf("debug-test-%d", i))
+			if i%2 == 0 {
+				req.Header.Set(DebugLogHeader, "1")
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+		}(i)
+	}
+	wg.Wait()
+
+	// 不在白名单中的请求被拒绝
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "198.51.100.1:1234"
+	req.Header.Set(RequestIDHeader, "debug-test-denied")
+	req.Header.Set(DebugLogHeader, "1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.NoError(t, logger.GetLogger().Sync())
+	data, err := os.ReadFile(filepath.Join(logDir, "app.log"))
+	require.NoError(t, err)
+
+	debugLines := map[string]bool{}
+	var audits, rejected int
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case strings.Contains(line, "per request debug line"):
+			for i := 0; i < 20; i++ {
+				if strings.Contains(line, fmt.Sprintf(`"debug-test-%d"`, i)) {
+					debugLines[fmt.Sprintf("debug-test-%d", i)] = true
+				}
+			}
+			assert.NotContains(t, line, "debug-test-denied")
+		case strings.Contains(line, "request debug logging enabled"):
+			audits++
+		case strings.Contains(line, "request debug logging rejected"):
+			rejected++
+		}
+	}
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("debug-test-%d", i)
+		assert.Equal(t, i%2 == 0, debugLines[id], id)
+	}
+	assert.Equal(t, 10, audits)
+	assert.Equal(t, 1, rejected)
+}
+
+func TestHMACSigned(t *testing.T) {
+	secret := []byte("s3cret")
+	auth := HMACSigned(secret, time.Minute)
+
+	check := func(sig string) bool {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/orders", nil)
+		c.Request.Header.Set(DebugLogSignatureHeader, sig)
+		return auth(c)
+	}
+
+	sig := SignDebugLog(secret, time.Now(), http.MethodGet, "/orders")
+	assert.True(t, check(sig))
+	// 同一个签名不能重放
+	assert.False(t, check(sig))
+	assert.True(t, check(SignDebugLog(secret, time.Now(), http.MethodGet, "/orders")))
+	assert.False(t, check(SignDebugLog(secret, time.Now(), http.MethodGet, "/other")))
+	assert.False(t, check(SignDebugLog([]byte("wrong"), time.Now(), http.MethodGet, "/orders")))
+	assert.False(t, check(SignDebugLog(secret, time.Now().Add(-time.Hour), http.MethodGet, "/orders")))
+	assert.False(t, check("garbage"))
+
+	// nonce 也在签名范围内, 不能替换
+	ts, rest, _ := strings.Cut(SignDebugLog(secret, time.Now(), http.MethodGet, "/orders"), ".")
+	_, mac, _ := strings.Cut(rest, ".")
+	assert.False(t, check(ts+".forged."+mac))
+}
+
+func TestAllowCIDRsInvalid(t *testing.T) {
+	_, err := AllowCIDRs("not-a-cidr")
+	assert.Error(t, err)
+}
@@ -1,33 +1,131 @@
 package middleware
 
 import (
+	"context"
+
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
 	"github.com/double12gzh/zap-demo/logger"
 )
 
-const RequestIDHeader = "X-Request-Id"
+const (
+	RequestIDHeader = "X-Request-Id"
+
+	// InvalidRequestIDField 记录被替换掉的非法 request id (已截断并转义)
+	InvalidRequestIDField = "invalid_request_id"
+)
+
+// Option 配置 RequestIDMiddleware
+type Option func(*options)
 
-func NewRequestID() string {
-	return "unique-request-id"
+type options struct {
+	generator IDGenerator
+	validate  func(string) bool
+	debugAuth DebugAuthorizer
+}
+
+// WithGenerator 指定生成 request id 使用的生成器, 默认为 DefaultGenerator
+func WithGenerator(g IDGenerator) Option {
+	return func(o *options) {
+		o.generator = g
+	}
+}
+
+// WithValidator 替换默认的 request id 校验规则 ValidRequestID
+func WithValidator(validate func(string) bool) Option {
+	return func(o *options) {
+		o.validate = validate
+	}
 }
 
 // RequestIDMiddleware 注入/生成 request id 并写入 context
 // RequestIDMiddleware 返回一个Gin中间件，用于为请求设置和记录请求ID
-func RequestIDMiddleware() gin.HandlerFunc {
+// 外部传入的非法 request id 会被替换为新生成的 id, 原值记录在 InvalidRequestIDField 字段中
+//
+// 同时处理 W3C Trace Context: 校验上游的 traceparent 并为本次请求生成子 span,
+// 上游没有传入时开启新的 trace. trace 信息写入日志字段、context 以及响应头.
+// 没有可用的 X-Request-Id 但上游传入了合法 traceparent 时, 使用 trace id 作为 request id
+func RequestIDMiddleware(opts ...Option) gin.HandlerFunc {
+	o := options{
+		generator: DefaultGenerator,
+		validate:  ValidRequestID,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	return func(c *gin.Context) {
+		fields := make([]zap.Field, 0, 5)
+
+		tc, upstream := traceContextFromRequest(c)
+		fields = append(fields,
+			zap.String(TraceIDField, tc.TraceID),
+			zap.String(SpanIDField, tc.SpanID),
+			zap.String(TraceFlagsField, tc.Flags),
+		)
+
 		reqID := c.GetHeader(RequestIDHeader)
+		if reqID != "" && !o.validate(reqID) {
+			fields = append(fields, zap.String(InvalidRequestIDField, sanitizeRequestID(reqID)))
+			reqID = ""
+		}
 		if reqID == "" {
-			reqID = NewRequestID()
+			if upstream {
+				reqID = tc.TraceID
+			} else {
+				reqID = o.generator.NewID()
+			}
 		}
+		fields = append(fields, zap.String(RequestIDHeader, reqID))
 
 		l := logger.FromContext(c.Request.Context())
-		l = l.WithFields(zap.String(RequestIDHeader, reqID))
+		l = l.WithFields(fields...)
+		l = o.debugLogger(c, l)
 		ctx := logger.NewContextWithValue(c.Request.Context(), l)
+		ctx = ContextWithTraceContext(ctx, tc)
+		ctx = ContextWithRequestID(ctx, reqID)
 
 		c.Request = c.Request.WithContext(ctx)
 		c.Header(RequestIDHeader, reqID)
+		c.Header(TraceparentHeader, tc.Traceparent())
+		if tc.State != "" {
+			c.Header(TracestateHeader, tc.State)
+		}
+		c.Next()
+	}
+}
+
+// LoggerMiddleware 把 l 写入请求的 context, 之后的中间件和 handler 通过
+// logger.FromContext 使用它而不是全局 logger. 需要放在 RequestIDMiddleware 之前
+func LoggerMiddleware(l *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(logger.NewContextWithValue(c.Request.Context(), l))
 		c.Next()
 	}
 }
+
+// traceContextFromRequest 返回本次请求的 TraceContext.
+// upstream 表示是否基于上游合法的 traceparent 生成
+func traceContextFromRequest(c *gin.Context) (tc TraceContext, upstream bool) {
+	parent, err := ParseTraceparent(c.GetHeader(TraceparentHeader))
+	if err != nil {
+		// traceparent 非法时 tracestate 也必须丢弃
+		return NewTraceContext(), false
+	}
+	parent.State = c.GetHeader(TracestateHeader)
+	return parent.Child(), true
+}
+
+type requestIDKey struct{}
+
+// ContextWithRequestID 把 request id 保存到 context 中, 供下游调用透传使用
+func ContextWithRequestID(ctx context.Context, reqID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, reqID)
+}
+
+// RequestIDFromContext 从 context 中取出 request id, 不存在时返回空字符串
+func RequestIDFromContext(ctx context.Context) string {
+	reqID, _ := ctx.Value(requestIDKey{}).(string)
+	return reqID
+}
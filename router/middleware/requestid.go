@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+)
+
+const (
+	// MaxRequestIDLength 是允许透传的外部 request id 的最大长度
+	MaxRequestIDLength = 128
+
+	// crockford base32 字母表, ULID 使用
+	crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// IDGenerator 生成 request id
+type IDGenerator interface {
+	NewID() string
+}
+
+// IDGeneratorFunc 允许使用普通函数作为 IDGenerator
+type IDGeneratorFunc func() string
+
+// NewID implements IDGenerator
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+var (
+	// UUIDv4Generator 生成随机的 UUIDv4
+	UUIDv4Generator IDGenerator = IDGeneratorFunc(NewUUIDv4)
+	// UUIDv7Generator 生成按时间排序的 UUIDv7
+	UUIDv7Generator IDGenerator = IDGeneratorFunc(NewUUIDv7)
+	// ULIDGenerator 生成按时间排序的 ULID
+	ULIDGenerator IDGenerator = IDGeneratorFunc(NewULID)
+
+	// DefaultGenerator 是 NewRequestID 使用的生成器
+	DefaultGenerator = UUIDv4Generator
+)
+
+// NewRequestID 使用 DefaultGenerator 生成一个新的 request id
+func NewRequestID() string {
+	return DefaultGenerator.NewID()
+}
+
+// NewUUIDv4 returns a random (version 4) UUID in its canonical string form.
+func NewUUIDv4() string {
+	var u [16]byte
+	mustReadRandom(u[:])
+	u[6] = (u[6] & 0x0f) | 0x40 // version 4
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return formatUUID(u)
+}
+
+// NewUUIDv7 returns a time-ordered (version 7) UUID in its canonical string form.
+// The first 48 bits hold the unix timestamp in milliseconds, the rest is random.
+func NewUUIDv7() string {
+	var u [16]byte
+	mustReadRandom(u[6:])
+	putUint48(u[:6], uint64(time.Now().UnixMilli()))
+	u[6] = (u[6] & 0x0f) | 0x70 // version 7
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return formatUUID(u)
+}
+
+// NewULID returns a ULID: a 48 bit millisecond timestamp followed by 80 random
+// bits, encoded as 26 characters of Crockford base32.
+func NewULID() string {
+	var id [16]byte
+	mustReadRandom(id[6:])
+	putUint48(id[:6], uint64(time.Now().UnixMilli()))
+
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+
+	// 128 bit 编码为 26 个字符, 每个字符 5 bit, 最高位字符只使用 3 bit
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordAlphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// ValidRequestID 判断外部传入的 request id 是否可以直接使用:
+// 非空, 长度不超过 MaxRequestIDLength, 只包含字母、数字以及 - _ . : 字符
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > MaxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if !isRequestIDChar(id[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isRequestIDChar(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	case c == '-', c == '_', c == '.', c == ':':
+		return true
+	}
+	return false
+}
+
+// sanitizeRequestID 把非法的 request id 转换成适合写入日志的形式:
+// 截断到 MaxRequestIDLength 并把非法字符替换为 '?'
+func sanitizeRequestID(id string) string {
+	if len(id) > MaxRequestIDLength {
+		id = id[:MaxRequestIDLength]
+	}
+	b := []byte(id)
+	for i := range b {
+		if !isRequestIDChar(b[i]) {
+			b[i] = '?'
+		}
+	}
+	return string(b)
+}
+
+func formatUUID(u [16]byte) string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+func putUint48(b []byte, v uint64) {
+	b[0] = byte(v >> 40)
+	b[1] = byte(v >> 32)
+	b[2] = byte(v >> 24)
+	b[3] = byte(v >> 16)
+	b[4] = byte(v >> 8)
+	b[5] = byte(v)
+}
+
+func mustReadRandom(b []byte) {
+	// crypto/rand.Read 按文档不会返回错误, 失败时会直接崩溃
+	_, _ = rand.Read(b)
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/double12gzh/zap-demo/logger"
+)
+
+// logDir 保存测试期间全局 logger 写入的日志文件
+var logDir string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "middleware-test")
+	if err != nil {
+		panic(err)
+	}
+	logDir = dir
+	err = logger.InitLogger(&logger.Config{
+		Filename:      filepath.Join(dir, "app.log"),
+		ErrorFilename: filepath.Join(dir, "error.log"),
+	})
+	if err != nil {
+		panic(err)
+	}
+	gin.SetMode(gin.TestMode)
+
+	code := m.Run()
+	_ = logger.GetLogger().Close(context.Background())
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestGeneratorFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		gen     IDGenerator
+		pattern string
+	}{
+		{"uuidv4", UUIDv4Generator, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{"uuidv7", UUIDv7Generator, `^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{"ulid", ULIDGenerator, `^[0-7][0-9A-HJKMNP-TV-Z]{25}$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := tt.gen.NewID()
+			assert.Regexp(t, regexp.MustCompile(tt.pattern), id)
+			assert.True(t, ValidRequestID(id))
+		})
+	}
+}
+
+func TestGeneratorsAreTimeOrdered(t *testing.T) {
+	for _, gen := range []IDGenerator{UUIDv7Generator, ULIDGenerator} {
+		a := gen.NewID()
+		// 时间戳精度为毫秒, 等待进入下一个毫秒
+		waitNextMillisecond()
+		b := gen.NewID()
+		assert.Less(t, strings.ToLower(a), strings.ToLower(b))
+	}
+}
+
+func TestGeneratorsConcurrentUniqueness(t *testing.T) {
+	const (
+		workers = 32
+		perGo   = 2000
+	)
+	for name, gen := range map[string]IDGenerator{
+		"uuidv4": UUIDv4Generator,
+		"uuidv7": UUIDv7Generator,
+		"ulid":   ULIDGenerator,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var (
+				mu   sync.Mutex
+				seen = make(map[string]struct{}, workers*perGo)
+				wg   sync.WaitGroup
+			)
+			for i := 0; i < workers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ids := make([]string, perGo)
+					for j := range ids {
+						ids[j] = gen.NewID()
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					for _, id := range ids {
+						seen[id] = struct{}{}
+					}
+				}()
+			}
+			wg.Wait()
+			assert.Len(t, seen, workers*perGo)
+		})
+	}
+}
+
+func TestValidRequestID(t *testing.T) {
+	assert.True(t, ValidRequestID("test-trace-5"))
+	assert.True(t, ValidRequestID("svc:abc_1.2"))
+	assert.False(t, ValidRequestID(""))
+	assert.False(t, ValidRequestID("line\nbreak"))
+	assert.False(t, ValidRequestID("has space"))
+	assert.False(t, ValidRequestID(strings.Repeat("a", MaxRequestIDLength+1)))
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	r := gin.New()
+	r.Use(RequestIDMiddleware(WithGenerator(IDGeneratorFunc(func() string { return "generated" }))))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"missing", "", "generated"},
+		{"valid", "client-id-1", "client-id-1"},
+		{"invalid charset", "bad\r\nid", "generated"},
+		{"too long", strings.Repeat("x", 10*1024), "generated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header[RequestIDHeader] = []string{tt.header}
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.want, w.Header().Get(RequestIDHeader))
+		})
+	}
+}
+
+func TestSanitizeRequestID(t *testing.T) {
+	assert.Equal(t, "bad??id", sanitizeRequestID("bad\r\nid"))
+	assert.Len(t, sanitizeRequestID(strings.Repeat("x", 1000)), MaxRequestIDLength)
+}
+
+func waitNextMillisecond() {
+	start := time.Now().UnixMilli()
+	for time.Now().UnixMilli() == start {
+		time.Sleep(100 * time.Microsecond)
+	}
+}
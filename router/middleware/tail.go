@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/double12gzh/zap-demo/logger"
+)
+
+// DefaultTailLimit 是每个请求最多缓存的日志条数
+const DefaultTailLimit = 256
+
+// TailOption 配置 TailLogMiddleware
+type TailOption func(*tailOptions)
+
+type tailOptions struct {
+	limit   int
+	level   zapcore.Level
+	summary bool
+}
+
+// WithTailLimit 设置每个请求最多缓存的日志条数, 超出后丢弃最早的日志
+func WithTailLimit(n int) TailOption {
+	return func(o *tailOptions) {
+		o.limit = n
+	}
+}
+
+// WithTailLevel 设置缓存的日志级别上限: 低于 level 的日志被缓存, 其余直接输出.
+// 默认为 warn, 即缓存 debug 和 info
+func WithTailLevel(level zapcore.Level) TailOption {
+	return func(o *tailOptions) {
+		o.level = level
+	}
+}
+
+// WithTailSummary 请求成功时输出一条汇总日志, 记录被丢弃的日志条数
+func WithTailSummary(enabled bool) TailOption {
+	return func(o *tailOptions) {
+		o.summary = enabled
+	}
+}
+
+// TailLogMiddleware 返回一个按请求结果决定是否输出日志的 Gin 中间件:
+// 请求期间通过 context logger 输出的 debug/info 日志先缓存在内存中,
+// 请求以 5xx 结束或发生 panic 时按顺序全部输出, 否则丢弃.
+// 需要放在 RequestIDMiddleware 之后, 才能让缓存的日志带上 request id
+func TailLogMiddleware(opts ...TailOption) gin.HandlerFunc {
+	o := tailOptions{
+		limit: DefaultTailLimit,
+		level: zapcore.WarnLevel,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(c *gin.Context) {
+		base := logger.FromContext(c.Request.Context())
+		buf := logger.NewTailBuffer(o.limit)
+		l := base.WithTailBuffer(buf, o.level)
+		c.Request = c.Request.WithContext(logger.NewContextWithValue(c.Request.Context(), l))
+
+		defer func() {
+			if r := recover(); r != nil {
+				written, dropped := buf.Flush()
+				base.Error("request panicked, flushed buffered logs",
+					zap.Any("panic", r),
+					zap.Int("flushed", written),
+					zap.Int("dropped", dropped),
+				)
+				panic(r)
+			}
+		}()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if status >= http.StatusInternalServerError {
+			written, dropped := buf.Flush()
+			base.Warn("request failed, flushed buffered logs",
+				zap.Int("status", status),
+				zap.Int("flushed", written),
+				zap.Int("dropped", dropped),
+			)
+			return
+		}
+
+		discarded := buf.Discard()
+		if o.summary {
+			base.Info("request completed",
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.Request.URL.Path),
+				zap.Int("status", status),
+				zap.Int("discarded", discarded),
+			)
+		}
+	}
+}
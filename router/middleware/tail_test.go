@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/double12gzh/zap-demo/logger"
+)
+
+func TestTailLogMiddleware(t *testing.T) {
+	r := gin.New()
+	r.Use(gin.Recovery(), RequestIDMiddleware(), TailLogMiddleware(WithTailSummary(true)))
+	r.GET("/status/:code", func(c *gin.Context) {
+		l := logger.FromContext(c.Request.Context())
+		l.Debug("tail debug " + c.Param("code"))
+		l.Info("tail info " + c.Param("code"))
+		switch c.Param("code") {
+		case "500":
+			c.Status(http.StatusInternalServerError)
+		case "panic":
+			panic("boom")
+		default:
+			c.Status(http.StatusOK)
+		}
+	})
+
+	for _, code := range []string{"200", "500", "panic"} {
+		req := httptest.NewRequest(http.MethodGet, "/status/"+code, nil)
+		req.Header.Set(RequestIDHeader, "tail-"+code)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	require.NoError(t, logger.GetLogger().Sync())
+	data, err := os.ReadFile(filepath.Join(logDir, "app.log"))
+	require.NoError(t, err)
+	logs := string(data)
+
+	assert.NotContains(t, logs, "tail debug 200")
+	assert.NotContains(t, logs, "tail info 200")
+	assert.Contains(t, logs, `"msg":"request completed"`)
+
+	assert.Contains(t, logs, "tail debug 500")
+	assert.Contains(t, logs, "tail info 500")
+	assert.Contains(t, logs, "tail debug panic")
+	assert.Contains(t, logs, "request panicked, flushed buffered logs")
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/double12gzh/zap-demo/logger"
+)
+
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+
+	TraceIDField    = logger.TraceIDKey
+	SpanIDField     = logger.SpanIDKey
+	TraceFlagsField = logger.TraceFlagsKey
+
+	traceparentVersion = "00"
+	traceparentLength  = 55 // 00-<32 hex>-<16 hex>-<2 hex>
+	sampledFlags       = "01"
+)
+
+var (
+	zeroTraceID = strings.Repeat("0", 32)
+	zeroSpanID  = strings.Repeat("0", 16)
+
+	ErrInvalidTraceparent = errors.New("invalid traceparent")
+)
+
+// TraceContext 描述一次请求的 W3C Trace Context
+// 所有 id 都是小写十六进制字符串
+type TraceContext struct {
+	TraceID  string // 32 位十六进制
+	SpanID   string // 当前请求的 span id, 16 位十六进制
+	ParentID string // 上游传入的 span id, 新建 trace 时为空
+	Flags    string // 2 位十六进制, 例如 "01" 表示 sampled
+	State    string // 原样透传的 tracestate
+}
+
+// ParseTraceparent 解析并校验 traceparent 头部
+// 返回的 TraceContext 中 SpanID 为上游的 parent-id, 调用方通常需要再调用 Child
+func ParseTraceparent(s string) (TraceContext, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < traceparentLength {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+
+	version := s[0:2]
+	if !isLowerHex(version) || version == "ff" {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+	// version 00 必须严格等长; 更高的版本允许在末尾追加 "-xxx"
+	if len(s) > traceparentLength && (version == traceparentVersion || s[traceparentLength] != '-') {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+	if s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+
+	tc := TraceContext{
+		TraceID: s[3:35],
+		SpanID:  s[36:52],
+		Flags:   s[53:55],
+	}
+	if !isLowerHex(tc.TraceID) || tc.TraceID == zeroTraceID ||
+		!isLowerHex(tc.SpanID) || tc.SpanID == zeroSpanID ||
+		!isLowerHex(tc.Flags) {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+	return tc, nil
+}
+
+// NewTraceContext 开启一个新的 trace
+func NewTraceContext() TraceContext {
+	return TraceContext{
+		TraceID: randomHex(16),
+		SpanID:  randomHex(8),
+		Flags:   sampledFlags,
+	}
+}
+
+// Child 返回当前 span 的子 span: 沿用 trace id 和 flags, 生成新的 span id
+func (tc TraceContext) Child() TraceContext {
+	return TraceContext{
+		TraceID:  tc.TraceID,
+		SpanID:   randomHex(8),
+		ParentID: tc.SpanID,
+		Flags:    tc.Flags,
+		State:    tc.State,
+	}
+}
+
+// Traceparent 返回 version 00 格式的 traceparent 头部
+func (tc TraceContext) Traceparent() string {
+	return traceparentVersion + "-" + tc.TraceID + "-" + tc.SpanID + "-" + tc.Flags
+}
+
+// IsValid 判断 trace id 和 span id 是否都有效
+func (tc TraceContext) IsValid() bool {
+	return len(tc.TraceID) == 32 && tc.TraceID != zeroTraceID &&
+		len(tc.SpanID) == 16 && tc.SpanID != zeroSpanID
+}
+
+type traceContextKey struct{}
+
+// ContextWithTraceContext 把 TraceContext 保存到 context 中
+func ContextWithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// TraceContextFromContext 从 context 中取出 TraceContext
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc, ok
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	for {
+		mustReadRandom(b)
+		// 全 0 的 id 是非法的, 概率极低但仍需要重试
+		for _, v := range b {
+			if v != 0 {
+				return hex.EncodeToString(b)
+			}
+		}
+	}
+}
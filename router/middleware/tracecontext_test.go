@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceparent(t *testing.T) {
+	tc, err := ParseTraceparent(validTraceparent)
+	require.NoError(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", tc.SpanID)
+	assert.Equal(t, "01", tc.Flags)
+	assert.Equal(t, validTraceparent, tc.Traceparent())
+
+	// 更高版本允许追加字段
+	_, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	assert.NoError(t, err)
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+	}
+	for _, s := range invalid {
+		_, err := ParseTraceparent(s)
+		assert.ErrorIs(t, err, ErrInvalidTraceparent, s)
+	}
+}
+
+func TestTraceContextChild(t *testing.T) {
+	parent, err := ParseTraceparent(validTraceparent)
+	require.NoError(t, err)
+
+	child := parent.Child()
+	assert.Equal(t, parent.TraceID, child.TraceID)
+	assert.Equal(t, parent.SpanID, child.ParentID)
+	assert.NotEqual(t, parent.SpanID, child.SpanID)
+	assert.True(t, child.IsValid())
+}
+
+func TestRequestIDMiddlewareTraceContext(t *testing.T) {
+	var got TraceContext
+	r := gin.New()
+	r.Use(RequestIDMiddleware())
+	r.GET("/", func(c *gin.Context) {
+		got, _ = TraceContextFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	t.Run("upstream traceparent", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(TraceparentHeader, validTraceparent)
+		req.Header.Set(TracestateHeader, "vendor=value")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", got.TraceID)
+		assert.Equal(t, "00f067aa0ba902b7", got.ParentID)
+		assert.NotEqual(t, "00f067aa0ba902b7", got.SpanID)
+		assert.Equal(t, got.Traceparent(), w.Header().Get(TraceparentHeader))
+		assert.Equal(t, "vendor=value", w.Header().Get(TracestateHeader))
+		// 没有 X-Request-Id 时使用 trace id
+		assert.Equal(t, got.TraceID, w.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("explicit request id wins", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(TraceparentHeader, validTraceparent)
+		req.Header.Set(RequestIDHeader, "client-id")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, "client-id", w.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("invalid traceparent starts new trace", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(TraceparentHeader, "garbage")
+		req.Header.Set(TracestateHeader, "vendor=value")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.True(t, got.IsValid())
+		assert.Empty(t, got.ParentID)
+		assert.Empty(t, w.Header().Get(TracestateHeader))
+		assert.NotEqual(t, got.TraceID, w.Header().Get(RequestIDHeader))
+	})
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/double12gzh/zap-demo/logger"
+)
+
+// DefaultRedactKeys 是默认需要脱敏的 body 字段名(不区分大小写)
+var DefaultRedactKeys = []string{
+	"password", "passwd", "secret", "token", "access_token", "refresh_token",
+	"authorization", "api_key", "apikey",
+}
+
+const redactedValue = "***"
+
+// Redactor 在 body 写入日志前对其脱敏
+type Redactor func(body []byte) []byte
+
+// NewRedactor 返回一个按字段名脱敏的 Redactor,
+// 同时处理 JSON 形式 "key": value 和表单形式 key=value.
+// 日志中的 body 是截断后的, 所以缺少结尾引号的字符串和数字、布尔等非字符串值也会被脱敏
+func NewRedactor(keys ...string) Redactor {
+	if len(keys) == 0 {
+		return func(body []byte) []byte { return body }
+	}
+	quoted := make([]string, len(keys))
+	for i, k := range keys {
+		quoted[i] = regexp.QuoteMeta(k)
+	}
+	alt := strings.Join(quoted, "|")
+	// 值为完整的字符串、截断在末尾的字符串, 或到 , } ] 或空白为止的数字、布尔等值
+	jsonRe := regexp.MustCompile(`(?i)("(?:` + alt + `)"\s*:\s*)(?:"(?:[^"\\]|\\.)*(?:"|\\?$)|[^"\s,{}\[\]]+)`)
+	formRe := regexp.MustCompile(`(?i)((?:^|&)(?:` + alt + `)=)[^&]*`)
+
+	return func(body []byte) []byte {
+		body = jsonRe.ReplaceAll(body, []byte(`${1}"`+redactedValue+`"`))
+		return formRe.ReplaceAll(body, []byte(`${1}`+redactedValue))
+	}
+}
+
+// TransportOption 配置 NewTransport
+type TransportOption func(*transport)
+
+// WithBodyLogging 记录请求和响应 body 的前 maxBytes 字节, maxBytes <= 0 时不记录.
+// 记录响应 body 时 RoundTrip 会等到读完这 maxBytes 字节才返回,
+// 因此 text/event-stream 以及长度未知的分块响应不记录 body, 以免阻塞流式响应.
+// 长度未知的请求 body 同样不记录
+func WithBodyLogging(maxBytes int) TransportOption {
+	return func(t *transport) {
+		t.maxBodyBytes = maxBytes
+	}
+}
+
+// WithRedactor 替换默认的 body 脱敏规则
+func WithRedactor(r Redactor) TransportOption {
+	return func(t *transport) {
+		t.redact = r
+	}
+}
+
+type transport struct {
+	base         http.RoundTripper
+	maxBodyBytes int
+	redact       Redactor
+}
+
+// NewTransport 包装 base, 返回一个感知 logger 的 http.RoundTripper:
+// 把 context 中的 request id 和 traceparent 写入请求头,
+// 并通过 logger.FromContext 记录每次调用的 method、host、path、status、耗时和错误.
+// base 为 nil 时使用 http.DefaultTransport
+func NewTransport(base http.RoundTripper, opts ...TransportOption) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &transport{
+		base:   base,
+		redact: NewRedactor(DefaultRedactKeys...),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	// RoundTripper 不允许修改传入的请求, 先复制一份
+	req = req.Clone(ctx)
+
+	reqID := RequestIDFromContext(ctx)
+	if reqID != "" && req.Header.Get(RequestIDHeader) == "" {
+		req.Header.Set(RequestIDHeader, reqID)
+	}
+	if tc, ok := TraceContextFromContext(ctx); ok && req.Header.Get(TraceparentHeader) == "" {
+		req.Header.Set(TraceparentHeader, tc.Traceparent())
+		if tc.State != "" {
+			req.Header.Set(TracestateHeader, tc.State)
+		}
+	}
+
+	fields := []zap.Field{
+		zap.String("method", req.Method),
+		zap.String("host", req.URL.Host),
+		zap.String("path", req.URL.Path),
+	}
+	// 长度未知 (ContentLength 为 0 而 Body 不为空) 的请求 body 可能是流或 pipe, 同步读取会阻塞, 甚至在写入方等待响应时死锁
+	if t.maxBodyBytes > 0 && req.Body != nil && req.Body != http.NoBody && req.ContentLength > 0 {
+		var prefix []byte
+		prefix, req.Body = peekBody(req.Body, t.maxBodyBytes)
+		fields = append(fields, zap.ByteString("request_body", t.redact(prefix)))
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	fields = append(fields, zap.Duration("duration", time.Since(start)))
+
+	l := logger.FromContext(ctx)
+	if err != nil {
+		l.Error("http client request failed", append(fields, zap.Error(err))...)
+		return resp, err
+	}
+
+	fields = append(fields, zap.Int("status", resp.StatusCode))
+	if t.maxBodyBytes > 0 && resp.Body != nil && resp.Body != http.NoBody && !isStreaming(resp) {
+		var prefix []byte
+		prefix, resp.Body = peekBody(resp.Body, t.maxBodyBytes)
+		fields = append(fields, zap.ByteString("response_body", t.redact(prefix)))
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		l.Warn("http client request", fields...)
+	} else {
+		l.Info("http client request", fields...)
+	}
+	return resp, nil
+}
+
+// isStreaming 判断 resp 是否是流式响应: server-sent events, 或长度未知的分块响应
+func isStreaming(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "text/event-stream" || resp.ContentLength < 0
+}
+
+// peekBody 读取 body 的前 n 字节, 返回读取的内容以及一个可以完整读出原始数据的 body
+func peekBody(body io.ReadCloser, n int) ([]byte, io.ReadCloser) {
+	prefix, _ := io.ReadAll(io.LimitReader(body, int64(n)))
+	return prefix, struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(prefix), body), body}
+}
@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/double12gzh/zap-demo/logger"
+	"github.com/double12gzh/zap-demo/logger/logtest"
+)
+
+func TestTransportPropagatesHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		_, _ = io.WriteString(w, `{"ok":true,"token":"server-secret"}`)
+	}))
+	defer srv.Close()
+
+	tc := NewTraceContext()
+	tc.State = "vendor=value"
+	ctx := ContextWithRequestID(context.Background(), "req-transport-1")
+	ctx = ContextWithTraceContext(ctx, tc)
+
+	client := &http.Client{Transport: NewTransport(nil, WithBodyLogging(16))}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/v1/login",
+		strings.NewReader(`{"user":"bob","password":"hunter2"}`))
+	require.NoError(t, err)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	// 截取 body 用于日志不能影响真实的请求和响应
+	assert.Equal(t, `{"ok":true,"token":"server-secret"}`, string(body))
+	assert.Equal(t, "req-transport-1", got.Get(RequestIDHeader))
+	assert.Equal(t, tc.Traceparent(), got.Get(TraceparentHeader))
+	assert.Equal(t, "vendor=value", got.Get(TracestateHeader))
+	// 原始请求不应被修改
+	assert.Empty(t, req.Header.Get(RequestIDHeader))
+
+	require.NoError(t, logger.GetLogger().Sync())
+	logs, err := os.ReadFile(filepath.Join(logDir, "app.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(logs), `"path":"/v1/login"`)
+	assert.Contains(t, string(logs), `"status":200`)
+}
+
+func TestTransportKeepsCallerHeaders(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(RequestIDHeader)
+	}))
+	defer srv.Close()
+
+	ctx := ContextWithRequestID(context.Background(), "from-context")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set(RequestIDHeader, "explicit")
+
+	resp, err := (&http.Client{Transport: NewTransport(nil)}).Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, "explicit", got)
+}
+
+func TestRedactor(t *testing.T) {
+	redact := NewRedactor(DefaultRedactKeys...)
+
+	assert.Equal(t,
+		`{"user":"bob","Password":"***","nested":{"access_token":"***"}}`,
+		string(redact([]byte(`{"user":"bob","Password":"hunter2","nested":{"access_token":"a\"b"}}`))))
+	assert.Equal(t,
+		`user=bob&password=***&x=1`,
+		string(redact([]byte(`user=bob&password=hunter2&x=1`))))
+
+	// 截断在值中间的字符串, 以及非字符串的值
+	assert.Equal(t, `{"password":"***"`, string(redact([]byte(`{"password":"hunt`))))
+	assert.Equal(t, `{"password":"***"`, string(redact([]byte(`{"password":"a\`))))
+	assert.Equal(t, `{"api_key":"***","n":1}`, string(redact([]byte(`{"api_key":1234,"n":1}`))))
+	assert.Equal(t, `{"token":"***"}`, string(redact([]byte(`{"token":null}`))))
+	assert.Equal(t, `{"pin":"***"}`, string(NewRedactor("pin")([]byte(`{"pin":1234}`))))
+}
+
+func TestTransportRedactsTruncatedBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"token":"server-secret-value"}`)
+	}))
+	defer srv.Close()
+
+	l, rec := logtest.New(t)
+	ctx := logger.NewContextWithValue(context.Background(), l)
+	client := &http.Client{Transport: NewTransport(nil, WithBodyLogging(16))}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL,
+		strings.NewReader(`{"password":"hunter2sec"}`))
+	require.NoError(t, err)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_, err = io.Copy(io.Discard, resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	// 前 16 字节截断在密码和 token 的中间
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(rec.Output(), &entry))
+	assert.Equal(t, `{"password":"***"`, entry["request_body"])
+	assert.Equal(t, `{"token":"***"`, entry["response_body"])
+	assert.NotContains(t, string(rec.Output()), "hunt")
+	assert.NotContains(t, string(rec.Output()), "server-")
+}
+
+func TestTransportSkipsStreamingBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/events" {
+			w.Header().Set("Content-Type", "text/event-stream")
+		}
+		_, _ = io.WriteString(w, "data: hello\n\n")
+		// 未设置 Content-Length 的 Flush 使响应变为长度未知的分块响应
+		w.(http.Flusher).Flush()
+	}))
+	defer srv.Close()
+
+	for _, path := range []string{"/events", "/chunked"} {
+		l, rec := logtest.New(t)
+		ctx := logger.NewContextWithValue(context.Background(), l)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+path, nil)
+		require.NoError(t, err)
+
+		resp, err := (&http.Client{Transport: NewTransport(nil, WithBodyLogging(1024))}).Do(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		assert.Equal(t, "data: hello\n\n", string(body))
+		assert.Contains(t, string(rec.Output()), `"status":200`, path)
+		assert.NotContains(t, string(rec.Output()), "response_body", path)
+	}
+}
+
+func TestTransportSkipsStreamingRequestBody(t *testing.T) {
+	received := make(chan struct{})
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(received)
+		body, _ := io.ReadAll(r.Body)
+		got = string(body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	// 写入方等到服务端收到请求后才结束 body, 先同步读取 maxBodyBytes 会死锁
+	pr, pw := io.Pipe()
+	go func() {
+		_, _ = io.WriteString(pw, "chunk")
+		select {
+		case <-received:
+			_ = pw.Close()
+		case <-time.After(5 * time.Second):
+			_ = pw.CloseWithError(errors.New("request with a streaming body blocked"))
+		}
+	}()
+
+	l, rec := logtest.New(t)
+	ctx := logger.NewContextWithValue(context.Background(), l)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL, pr)
+	require.NoError(t, err)
+
+	resp, err := (&http.Client{Transport: NewTransport(nil, WithBodyLogging(1024))}).Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	assert.Equal(t, "chunk", got)
+	assert.Contains(t, string(rec.Output()), `"status":202`)
+	assert.NotContains(t, string(rec.Output()), "request_body")
+}
@@ -1,6 +1,7 @@
 package router
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -18,18 +19,55 @@ type Response struct {
 	Status  string      `json:"status"`
 }
 
-func ServHTTP() {
-	go func() {
-		for {
-			_ = logger.GetLogger().Sync()
-		}
-	}()
+// EngineOption 配置 NewEngine
+type EngineOption func(*engineOptions)
+
+type engineOptions struct {
+	logger      *logger.Logger
+	requestID   []middleware.Option
+	middlewares []gin.HandlerFunc
+}
+
+// WithLogger 指定请求使用的 logger, 默认为全局 logger
+func WithLogger(l *logger.Logger) EngineOption {
+	return func(o *engineOptions) {
+		o.logger = l
+	}
+}
+
+// WithRequestIDOptions 设置 RequestIDMiddleware 的选项
+func WithRequestIDOptions(opts ...middleware.Option) EngineOption {
+	return func(o *engineOptions) {
+		o.requestID = append(o.requestID, opts...)
+	}
+}
+
+// WithMiddleware 追加中间件, 在 RequestIDMiddleware 之后执行
+func WithMiddleware(mw ...gin.HandlerFunc) EngineOption {
+	return func(o *engineOptions) {
+		o.middlewares = append(o.middlewares, mw...)
+	}
+}
+
+// NewEngine 创建注册好中间件和路由的 gin.Engine.
+// 请求的 context 中注入指定的 logger, 不依赖全局 logger, 可以直接用 httptest 测试
+func NewEngine(opts ...EngineOption) *gin.Engine {
+	var o engineOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.logger == nil {
+		o.logger = logger.GetLogger()
+	}
 
 	// Create a new Gin router with default middleware
-	r := gin.Default()
+	r := gin.New()
+	r.Use(gin.Logger(), gin.Recovery())
 
-	// Add RequestID middleware
-	r.Use(middleware.RequestIDMiddleware())
+	// Add logger and RequestID middleware
+	r.Use(middleware.LoggerMiddleware(o.logger))
+	r.Use(middleware.RequestIDMiddleware(o.requestID...))
+	r.Use(o.middlewares...)
 
 	r.GET("/ping", func(c *gin.Context) {
 		demo.Demo(c.Request.Context())
@@ -42,6 +80,14 @@ func ServHTTP() {
 		})
 	})
 
+	return r
+}
+
+// ServHTTP 在 DefaultAddr 上启动服务, 收到 SIGINT/SIGTERM 后优雅退出并关闭全局 logger
+func ServHTTP() error {
+	// gin 的调试、访问和错误日志统一写入结构化日志
+	_ = logger.InstallGinWriters()
+
 	// Start the server on port 8080
-	_ = r.Run(":8080")
+	return NewServer(NewEngine()).ListenAndServe(context.Background())
 }
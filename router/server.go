@@ -0,0 +1,244 @@
+package router
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+
+	"github.com/double12gzh/zap-demo/logger"
+)
+
+const (
+	// DefaultAddr 默认监听地址
+	DefaultAddr = ":8080"
+	// DefaultShutdownTimeout 默认等待处理中请求结束的时间
+	DefaultShutdownTimeout = 15 * time.Second
+	// DefaultLoggerCloseTimeout 默认刷新并关闭 logger 的时间
+	DefaultLoggerCloseTimeout = 5 * time.Second
+)
+
+// ServerConfig HTTP 服务配置, 零值字段使用默认值.
+// http.Server 的超时为指针: nil 使用默认值, 而 logger.Ptr(time.Duration(0)) 表示不超时,
+// 与 http.Server 相同, ReadHeaderTimeout 和 IdleTimeout 为 0 时沿用 ReadTimeout
+type ServerConfig struct {
+	Addr              string
+	ReadTimeout       *time.Duration // 默认 30s
+	ReadHeaderTimeout *time.Duration // 默认 10s
+	WriteTimeout      *time.Duration // 默认 30s
+	IdleTimeout       *time.Duration // 默认 120s
+	// ShutdownTimeout 收到退出信号后等待处理中请求的最长时间, 超时的请求被断开
+	ShutdownTimeout time.Duration
+	// LoggerCloseTimeout 请求结束后刷新并关闭 logger 的最长时间
+	LoggerCloseTimeout time.Duration
+
+	// 设置 TLSCertFile 和 TLSKeyFile, 或者设置带证书的 TLSConfig 时使用 HTTPS
+	TLSCertFile string
+	TLSKeyFile  string
+	TLSConfig   *tls.Config
+}
+
+func defaultServerConfig() ServerConfig {
+	return ServerConfig{
+		Addr:               DefaultAddr,
+		ReadTimeout:        logger.Ptr(30 * time.Second),
+		ReadHeaderTimeout:  logger.Ptr(10 * time.Second),
+		WriteTimeout:       logger.Ptr(30 * time.Second),
+		IdleTimeout:        logger.Ptr(120 * time.Second),
+		ShutdownTimeout:    DefaultShutdownTimeout,
+		LoggerCloseTimeout: DefaultLoggerCloseTimeout,
+	}
+}
+
+// ServerOption 配置 Server
+type ServerOption func(*Server)
+
+// WithServerConfig 设置服务配置, 零值或 nil 字段保持默认值
+func WithServerConfig(config ServerConfig) ServerOption {
+	return func(s *Server) {
+		def := s.config
+		if config.Addr == "" {
+			config.Addr = def.Addr
+		}
+		if config.ReadTimeout == nil {
+			config.ReadTimeout = def.ReadTimeout
+		}
+		if config.ReadHeaderTimeout == nil {
+			config.ReadHeaderTimeout = def.ReadHeaderTimeout
+		}
+		if config.WriteTimeout == nil {
+			config.WriteTimeout = def.WriteTimeout
+		}
+		if config.IdleTimeout == nil {
+			config.IdleTimeout = def.IdleTimeout
+		}
+		if config.ShutdownTimeout == 0 {
+			config.ShutdownTimeout = def.ShutdownTimeout
+		}
+		if config.LoggerCloseTimeout == 0 {
+			config.LoggerCloseTimeout = def.LoggerCloseTimeout
+		}
+		s.config = config
+	}
+}
+
+// WithServerLogger 设置记录服务生命周期的 logger, 退出时会关闭它.
+// 默认使用全局 logger
+func WithServerLogger(l *logger.Logger) ServerOption {
+	return func(s *Server) {
+		s.logger = l
+	}
+}
+
+// withSignals 用 c 代替进程收到的 SIGINT/SIGTERM, 测试中用它触发退出,
+// 而不必给整个测试进程发送信号
+func withSignals(c <-chan os.Signal) ServerOption {
+	return func(s *Server) {
+		s.signals = c
+	}
+}
+
+// Server 带优雅退出的 HTTP 服务.
+// 收到 SIGINT/SIGTERM 或者 ctx 结束时停止接收新连接, 等待处理中的请求结束,
+// 输出退出汇总日志, 最后在 LoggerCloseTimeout 内刷新并关闭 logger
+type Server struct {
+	config  ServerConfig
+	handler http.Handler
+	logger  *logger.Logger
+	signals <-chan os.Signal
+
+	inFlight atomic.Int64
+	served   atomic.Int64
+}
+
+// NewServer 创建服务
+func NewServer(handler http.Handler, opts ...ServerOption) *Server {
+	s := &Server{
+		config:  defaultServerConfig(),
+		handler: handler,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.logger == nil {
+		s.logger = logger.GetLogger()
+	}
+	return s
+}
+
+// ListenAndServe 监听 config.Addr 并处理请求, 直到收到退出信号或者 ctx 结束.
+// 正常退出时返回 nil
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve 在 ln 上处理请求, 直到收到退出信号或者 ctx 结束, ln 由 Serve 关闭
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	ctx, cancelServe := context.WithCancelCause(ctx)
+	defer cancelServe(nil)
+	signals, stop := s.signals, func() {}
+	if signals == nil {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
+		signals, stop = c, func() { signal.Stop(c) }
+	}
+	defer stop()
+	go func() {
+		select {
+		case sig := <-signals:
+			cancelServe(fmt.Errorf("received signal %s", sig))
+		case <-ctx.Done():
+		}
+	}()
+
+	srv := &http.Server{
+		Handler:           s.countRequests(s.handler),
+		ReadTimeout:       *s.config.ReadTimeout,
+		ReadHeaderTimeout: *s.config.ReadHeaderTimeout,
+		WriteTimeout:      *s.config.WriteTimeout,
+		IdleTimeout:       *s.config.IdleTimeout,
+		TLSConfig:         s.config.TLSConfig,
+	}
+	tlsEnabled := s.config.tlsEnabled()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if tlsEnabled {
+			serveErr <- srv.ServeTLS(ln, s.config.TLSCertFile, s.config.TLSKeyFile)
+		} else {
+			serveErr <- srv.Serve(ln)
+		}
+	}()
+	s.logger.Info("http server started", zap.String("addr", ln.Addr().String()), zap.Bool("tls", tlsEnabled))
+	started := time.Now()
+
+	var err error
+	select {
+	case err = <-serveErr:
+		// 服务异常退出, 不需要再等待请求
+		s.logger.Error("http server failed", zap.Error(err))
+	case <-ctx.Done():
+	}
+	// 恢复信号的默认行为, 再次收到信号时直接退出
+	stop()
+
+	if err == nil {
+		draining := s.inFlight.Load()
+		s.logger.Info("http server shutting down", zap.NamedError("reason", context.Cause(ctx)), zap.Int64("in_flight", draining))
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+		defer cancel()
+
+		begin := time.Now()
+		if err = srv.Shutdown(shutdownCtx); err != nil {
+			// 超时未结束的请求直接断开
+			err = multierr.Append(err, srv.Close())
+		}
+		s.logger.Info("http server stopped",
+			zap.Int64("served", s.served.Load()),
+			zap.Int64("drained", draining-s.inFlight.Load()),
+			zap.Int64("aborted", s.inFlight.Load()),
+			zap.Duration("shutdown_duration", time.Since(begin)),
+			zap.Duration("uptime", time.Since(started)),
+			zap.Error(err),
+		)
+	}
+
+	// 刷新并关闭 logger, 保证最后的日志落盘
+	closeCtx, cancel := context.WithTimeout(context.Background(), s.config.LoggerCloseTimeout)
+	defer cancel()
+	return multierr.Append(err, s.logger.Close(closeCtx))
+}
+
+// countRequests 统计处理中和已处理的请求数
+func (s *Server) countRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.inFlight.Add(1)
+		defer func() {
+			s.inFlight.Add(-1)
+			s.served.Add(1)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tlsEnabled 是否配置了证书
+func (c ServerConfig) tlsEnabled() bool {
+	if c.TLSCertFile != "" {
+		return true
+	}
+	return c.TLSConfig != nil && (len(c.TLSConfig.Certificates) > 0 || c.TLSConfig.GetCertificate != nil)
+}
@@ -0,0 +1,178 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/double12gzh/zap-demo/logger"
+)
+
+// startServer 在随机端口启动服务, 返回服务地址和 Serve 的返回值
+func startServer(t *testing.T, ctx context.Context, handler http.Handler, opts ...ServerOption) (string, <-chan error, string) {
+	t.Helper()
+	dir := t.TempDir()
+	l, err := logger.NewLogger(&logger.Config{
+		Filename:      filepath.Join(dir, "app.log"),
+		ErrorFilename: filepath.Join(dir, "error.log"),
+	})
+	require.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := NewServer(handler, append([]ServerOption{WithServerLogger(l)}, opts...)...)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve(ctx, ln)
+	}()
+	return "http://" + ln.Addr().String(), done, filepath.Join(dir, "app.log")
+}
+
+// findEntry 返回日志文件中第一条 msg 匹配的记录
+func findEntry(t *testing.T, file, msg string) map[string]any {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		entry := map[string]any{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		if entry["msg"] == msg {
+			return entry
+		}
+	}
+	t.Fatalf("no %q entry in %s", msg, data)
+	return nil
+}
+
+func TestServerGracefulShutdown(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	url, done, logFile := startServer(t, ctx, handler)
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	<-entered
+
+	// 收到退出信号后等待处理中的请求
+	cancel()
+	select {
+	case err := <-done:
+		t.Fatalf("server stopped before draining: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-done)
+	assert.Equal(t, http.StatusOK, <-status)
+
+	// 汇总日志在 logger 关闭时落盘
+	assert.EqualValues(t, 1, findEntry(t, logFile, "http server shutting down")["in_flight"])
+	stopped := findEntry(t, logFile, "http server stopped")
+	assert.EqualValues(t, 1, stopped["served"])
+	assert.EqualValues(t, 1, stopped["drained"])
+	assert.EqualValues(t, 0, stopped["aborted"])
+}
+
+func TestServerShutdownTimeout(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	url, done, logFile := startServer(t, ctx, handler, WithServerConfig(ServerConfig{ShutdownTimeout: 50 * time.Millisecond}))
+
+	go func() {
+		if resp, err := http.Get(url); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-entered
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.DeadlineExceeded)
+	stopped := findEntry(t, logFile, "http server stopped")
+	assert.EqualValues(t, 0, stopped["drained"])
+	assert.EqualValues(t, 1, stopped["aborted"])
+}
+
+func TestServerSignal(t *testing.T) {
+	signals := make(chan os.Signal, 1)
+	url, done, logFile := startServer(t, context.Background(), http.NotFoundHandler(), withSignals(signals))
+
+	// 先处理一个请求, 退出汇总中应计入它
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(url)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	signals <- syscall.SIGTERM
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop on SIGTERM")
+	}
+	assert.EqualValues(t, 1, findEntry(t, logFile, "http server stopped")["served"])
+	assert.Equal(t, "received signal terminated", findEntry(t, logFile, "http server shutting down")["reason"])
+}
+
+func TestServerListenError(t *testing.T) {
+	dir := t.TempDir()
+	l, err := logger.NewLogger(&logger.Config{Filename: filepath.Join(dir, "app.log"), ErrorFilename: filepath.Join(dir, "error.log")})
+	require.NoError(t, err)
+	defer l.Close(context.Background())
+
+	s := NewServer(http.NotFoundHandler(), WithServerLogger(l), WithServerConfig(ServerConfig{Addr: "invalid:address:80"}))
+	assert.Error(t, s.ListenAndServe(context.Background()))
+}
+
+func TestWithServerConfigTimeouts(t *testing.T) {
+	l := logger.NewLoggerWithCore(zapcore.NewNopCore())
+	s := NewServer(http.NotFoundHandler(), WithServerLogger(l), WithServerConfig(ServerConfig{
+		ReadTimeout:  logger.Ptr(time.Duration(0)),
+		WriteTimeout: logger.Ptr(time.Duration(0)),
+		IdleTimeout:  logger.Ptr(time.Minute),
+	}))
+
+	// 显式的 0 关闭超时, nil 保持默认值
+	assert.Zero(t, *s.config.ReadTimeout)
+	assert.Zero(t, *s.config.WriteTimeout)
+	assert.Equal(t, time.Minute, *s.config.IdleTimeout)
+	assert.Equal(t, *defaultServerConfig().ReadHeaderTimeout, *s.config.ReadHeaderTimeout)
+	assert.Equal(t, DefaultShutdownTimeout, s.config.ShutdownTimeout)
+}